@@ -0,0 +1,122 @@
+package protogen
+
+import (
+	"bytes"
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// An annotation maps an identifier in a generated file to the descriptor
+// it was generated from.
+type annotation struct {
+	identifier string
+	desc       protoreflect.Descriptor
+	offset     int // length of the file body when the annotation was made
+}
+
+// Annotate records that the next occurrence of identifier written to g
+// was generated from desc. It must be called before the identifier is
+// written. Response reports the annotations as the file's GeneratedCodeInfo.
+func (gen *Generator) Annotate(g *GeneratedFile, identifier string, desc protoreflect.Descriptor) {
+	g.annotations = append(g.annotations, annotation{
+		identifier: identifier,
+		desc:       desc,
+		offset:     g.buf.Len(),
+	})
+}
+
+// generatedCodeInfo resolves the annotations of g against its content.
+func (g *GeneratedFile) generatedCodeInfo() (*descriptorpb.GeneratedCodeInfo, error) {
+	if g.binary {
+		return nil, nil
+	}
+
+	info := &descriptorpb.GeneratedCodeInfo{}
+	if _, err := g.appendAnnotations(info, 0); err != nil {
+		return nil, err
+	}
+	if len(info.Annotation) == 0 {
+		return nil, nil
+	}
+	return info, nil
+}
+
+// appendAnnotations appends the annotations of g and its sections to info,
+// given that the content of g starts at offset start. It returns the offset
+// at which the content of g ends.
+func (g *GeneratedFile) appendAnnotations(info *descriptorpb.GeneratedCodeInfo, start int) (int, error) {
+	start += g.header.Len()
+
+	body := g.buf.Bytes()
+	for _, a := range g.annotations {
+		i := bytes.Index(body[a.offset:], []byte(a.identifier))
+		if i < 0 {
+			return 0, fmt.Errorf("%v: annotated identifier %q not found", g.filename, a.identifier)
+		}
+
+		begin := start + a.offset + i
+		info.Annotation = append(info.Annotation, &descriptorpb.GeneratedCodeInfo_Annotation{
+			Path:       sourcePath(a.desc),
+			SourceFile: proto.String(a.desc.ParentFile().Path()),
+			Begin:      proto.Int32(int32(begin)),
+			End:        proto.Int32(int32(begin + len(a.identifier))),
+		})
+	}
+
+	end := start + len(body)
+	for _, s := range g.sections {
+		var err error
+		if end, err = s.appendAnnotations(info, end); err != nil {
+			return 0, err
+		}
+	}
+	return end, nil
+}
+
+// sourcePath returns the path of desc within its FileDescriptorProto,
+// as used by SourceCodeInfo.
+func sourcePath(desc protoreflect.Descriptor) protoreflect.SourcePath {
+	var parent protoreflect.SourcePath
+	if _, ok := desc.Parent().(protoreflect.FileDescriptor); !ok && desc.Parent() != nil {
+		parent = sourcePath(desc.Parent())
+	}
+
+	var number int32
+	switch desc := desc.(type) {
+	case protoreflect.FileDescriptor:
+		return nil
+	case protoreflect.MessageDescriptor:
+		number = 4 // FileDescriptorProto.message_type
+		if parent != nil {
+			number = 3 // DescriptorProto.nested_type
+		}
+	case protoreflect.EnumDescriptor:
+		number = 5 // FileDescriptorProto.enum_type
+		if parent != nil {
+			number = 4 // DescriptorProto.enum_type
+		}
+	case protoreflect.FieldDescriptor:
+		switch {
+		case !desc.IsExtension():
+			number = 2 // DescriptorProto.field
+		case parent != nil:
+			number = 6 // DescriptorProto.extension
+		default:
+			number = 7 // FileDescriptorProto.extension
+		}
+	case protoreflect.OneofDescriptor:
+		number = 8 // DescriptorProto.oneof_decl
+	case protoreflect.EnumValueDescriptor:
+		number = 2 // EnumDescriptorProto.value
+	case protoreflect.ServiceDescriptor:
+		number = 6 // FileDescriptorProto.service
+	case protoreflect.MethodDescriptor:
+		number = 2 // ServiceDescriptorProto.method
+	}
+
+	path := append(protoreflect.SourcePath{}, parent...)
+	return append(path, number, int32(desc.Index()))
+}
@@ -0,0 +1,120 @@
+package protogen
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+// httpRuleNumber is the field number of the google.api.http method option.
+const httpRuleNumber = 72295728
+
+// An HTTPRule is a parsed google.api.HttpRule, mapping a method to an HTTP
+// endpoint for gRPC-HTTP transcoding.
+type HTTPRule struct {
+	Method string // HTTP method, e.g. "GET", or the kind of a custom pattern
+	Path   string // URL path template, e.g. "/v1/{name=shelves/*}"
+	Body   string // request field mapped to the HTTP body; "*" for all fields
+
+	AdditionalBindings []HTTPRule
+}
+
+// GetParsedHTTPRules returns the google.api.http rules set on the method.
+// The option is read from its wire encoding, so it is found whether or not
+// the google.api extension is linked into the plugin. It returns an empty
+// slice if the option is not set.
+func (method *Method) GetParsedHTTPRules() ([]HTTPRule, error) {
+	b, err := proto.Marshal(method.GetMethodOptions())
+	if err != nil {
+		return nil, err
+	}
+
+	rules := []HTTPRule{}
+	err = consumeBytesFields(b, func(num protowire.Number, v []byte) error {
+		if num != httpRuleNumber {
+			return nil
+		}
+		rule, err := parseHTTPRule(v)
+		if err != nil {
+			return err
+		}
+		rules = append(rules, rule)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("method %v: invalid google.api.http option: %v", method.Desc.FullName(), err)
+	}
+	return rules, nil
+}
+
+// parseHTTPRule parses the wire encoding of a google.api.HttpRule.
+func parseHTTPRule(b []byte) (HTTPRule, error) {
+	var rule HTTPRule
+	err := consumeBytesFields(b, func(num protowire.Number, v []byte) error {
+		switch num {
+		case 2:
+			rule.Method, rule.Path = "GET", string(v)
+		case 3:
+			rule.Method, rule.Path = "PUT", string(v)
+		case 4:
+			rule.Method, rule.Path = "POST", string(v)
+		case 5:
+			rule.Method, rule.Path = "DELETE", string(v)
+		case 6:
+			rule.Method, rule.Path = "PATCH", string(v)
+		case 7:
+			rule.Body = string(v)
+		case 8: // CustomHttpPattern
+			return consumeBytesFields(v, func(num protowire.Number, v []byte) error {
+				switch num {
+				case 1:
+					rule.Method = string(v)
+				case 2:
+					rule.Path = string(v)
+				}
+				return nil
+			})
+		case 11:
+			binding, err := parseHTTPRule(v)
+			if err != nil {
+				return err
+			}
+			rule.AdditionalBindings = append(rule.AdditionalBindings, binding)
+		}
+		return nil
+	})
+	return rule, err
+}
+
+// consumeBytesFields calls fn for each length-delimited field in b,
+// skipping fields of other wire types.
+func consumeBytesFields(b []byte, fn func(num protowire.Number, v []byte) error) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		if typ != protowire.BytesType {
+			n = protowire.ConsumeFieldValue(num, typ, b)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			b = b[n:]
+			continue
+		}
+
+		v, n := protowire.ConsumeBytes(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		if err := fn(num, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package protogen
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// An ImportManager tracks the Go packages imported by a generated file.
+// The zero value is ready to use.
+type ImportManager struct {
+	aliases map[string]string // import path -> package alias, "" if none
+}
+
+// Use records that importPath is needed, imported under packageAlias.
+// An empty packageAlias imports the package under its own name.
+func (m *ImportManager) Use(importPath, packageAlias string) {
+	if m.aliases == nil {
+		m.aliases = make(map[string]string)
+	}
+	m.aliases[importPath] = packageAlias
+}
+
+// Has reports whether importPath has been recorded.
+func (m *ImportManager) Has(importPath string) bool {
+	_, ok := m.aliases[importPath]
+	return ok
+}
+
+// Render returns an import declaration for the recorded packages, sorted by
+// import path, with standard library packages grouped before all others.
+// It returns "" if no packages have been recorded.
+func (m *ImportManager) Render() string {
+	if len(m.aliases) == 0 {
+		return ""
+	}
+
+	var std, other []string
+	for importPath := range m.aliases {
+		if isStandardImportPath(importPath) {
+			std = append(std, importPath)
+		} else {
+			other = append(other, importPath)
+		}
+	}
+	sort.Strings(std)
+	sort.Strings(other)
+
+	var b strings.Builder
+	b.WriteString("import (\n")
+	for i, group := range [][]string{std, other} {
+		if i > 0 && len(std) > 0 && len(other) > 0 {
+			b.WriteString("\n")
+		}
+		for _, importPath := range group {
+			b.WriteString("\t")
+			if alias := m.aliases[importPath]; alias != "" {
+				b.WriteString(alias + " ")
+			}
+			b.WriteString(strconv.Quote(importPath) + "\n")
+		}
+	}
+	b.WriteString(")\n")
+	return b.String()
+}
+
+// isStandardImportPath reports whether importPath looks like a standard
+// library package, that is, whether its first element contains no dot.
+func isStandardImportPath(importPath string) bool {
+	first, _, _ := strings.Cut(importPath, "/")
+	return !strings.Contains(first, ".")
+}
@@ -0,0 +1,27 @@
+package protogen
+
+import "strconv"
+
+// A NameCollisionDetector hands out unique identifiers within a scope, such
+// as a generated file. The zero value is ready to use.
+type NameCollisionDetector struct {
+	used map[string]bool
+}
+
+// Register records name and returns it if it has not been used yet.
+// Otherwise it returns the first unused name of the form name_N, for N
+// counting from 1, and reports the collision. The returned name is recorded
+// as used.
+func (d *NameCollisionDetector) Register(name string) (unique string, collision bool) {
+	if d.used == nil {
+		d.used = make(map[string]bool)
+	}
+
+	unique = name
+	for n := 1; d.used[unique]; n++ {
+		unique = name + "_" + strconv.Itoa(n)
+		collision = true
+	}
+	d.used[unique] = true
+	return unique, collision
+}
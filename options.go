@@ -0,0 +1,156 @@
+package protogen
+
+import (
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// An OptionExtractor reads custom options (extensions) from the options
+// message of a descriptor.
+type OptionExtractor struct {
+	options proto.Message
+}
+
+// ExtractFileOptions returns an OptionExtractor for the options of file f.
+func ExtractFileOptions(f *File) OptionExtractor {
+	return OptionExtractor{f.Desc.Options()}
+}
+
+// ExtractMessageOptions returns an OptionExtractor for the options of message.
+func ExtractMessageOptions(message *Message) OptionExtractor {
+	return OptionExtractor{message.Desc.Options()}
+}
+
+// ExtractFieldOptions returns an OptionExtractor for the options of field.
+func ExtractFieldOptions(field *Field) OptionExtractor {
+	return OptionExtractor{field.Desc.Options()}
+}
+
+// ExtractOneofOptions returns an OptionExtractor for the options of oneof o.
+func ExtractOneofOptions(o *Oneof) OptionExtractor {
+	return OptionExtractor{o.Desc.Options()}
+}
+
+// ExtractEnumOptions returns an OptionExtractor for the options of enum.
+func ExtractEnumOptions(enum *Enum) OptionExtractor {
+	return OptionExtractor{enum.Desc.Options()}
+}
+
+// ExtractEnumValueOptions returns an OptionExtractor for the options of enum value ev.
+func ExtractEnumValueOptions(ev *EnumValue) OptionExtractor {
+	return OptionExtractor{ev.Desc.Options()}
+}
+
+// ExtractServiceOptions returns an OptionExtractor for the options of service s.
+func ExtractServiceOptions(s *Service) OptionExtractor {
+	return OptionExtractor{s.Desc.Options()}
+}
+
+// ExtractMethodOptions returns an OptionExtractor for the options of method.
+func ExtractMethodOptions(method *Method) OptionExtractor {
+	return OptionExtractor{method.Desc.Options()}
+}
+
+// Get returns the value of the extension xt. It reports false if xt does
+// not extend the options message or is not set. The option is also found
+// if the options were parsed before xt was known, leaving it as an unknown
+// field.
+func (e OptionExtractor) Get(xt protoreflect.ExtensionType) (protoreflect.Value, bool) {
+	// Extensions built from the request's descriptors, e.g. with dynamicpb,
+	// extend a different descriptor instance of the same options message, so
+	// match by name rather than with proto.HasExtension.
+	xd := xt.TypeDescriptor()
+	m := e.options.ProtoReflect()
+	if xd.ContainingMessage().FullName() != m.Descriptor().FullName() {
+		return protoreflect.Value{}, false
+	}
+	if m.Has(xd) {
+		return m.Get(xd), true
+	}
+	if len(m.GetUnknown()) == 0 {
+		return protoreflect.Value{}, false
+	}
+
+	// Parse the options again with xt known.
+	b, err := proto.Marshal(e.options)
+	if err != nil {
+		return protoreflect.Value{}, false
+	}
+	types := new(protoregistry.Types)
+	if err := types.RegisterExtension(xt); err != nil {
+		return protoreflect.Value{}, false
+	}
+	options := m.New()
+	if err := (proto.UnmarshalOptions{Resolver: types}).Unmarshal(b, options.Interface()); err != nil {
+		return protoreflect.Value{}, false
+	}
+	if !options.Has(xd) {
+		return protoreflect.Value{}, false
+	}
+	return options.Get(xd), true
+}
+
+// GetBool returns the value of the singular bool extension xt.
+func (e OptionExtractor) GetBool(xt protoreflect.ExtensionType) (bool, bool) {
+	v, ok := e.getScalar(xt, protoreflect.BoolKind)
+	if !ok {
+		return false, false
+	}
+	return v.Bool(), true
+}
+
+// GetString returns the value of the singular string extension xt.
+func (e OptionExtractor) GetString(xt protoreflect.ExtensionType) (string, bool) {
+	v, ok := e.getScalar(xt, protoreflect.StringKind)
+	if !ok {
+		return "", false
+	}
+	return v.String(), true
+}
+
+// GetInt64 returns the value of the singular signed integer extension xt.
+func (e OptionExtractor) GetInt64(xt protoreflect.ExtensionType) (int64, bool) {
+	v, ok := e.getScalar(xt,
+		protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind)
+	if !ok {
+		return 0, false
+	}
+	return v.Int(), true
+}
+
+// GetUint64 returns the value of the singular unsigned integer extension xt.
+func (e OptionExtractor) GetUint64(xt protoreflect.ExtensionType) (uint64, bool) {
+	v, ok := e.getScalar(xt,
+		protoreflect.Uint32Kind, protoreflect.Fixed32Kind,
+		protoreflect.Uint64Kind, protoreflect.Fixed64Kind)
+	if !ok {
+		return 0, false
+	}
+	return v.Uint(), true
+}
+
+// GetFloat64 returns the value of the singular float or double extension xt.
+func (e OptionExtractor) GetFloat64(xt protoreflect.ExtensionType) (float64, bool) {
+	v, ok := e.getScalar(xt, protoreflect.FloatKind, protoreflect.DoubleKind)
+	if !ok {
+		return 0, false
+	}
+	return v.Float(), true
+}
+
+// getScalar returns the value of xt if it is a singular extension of one
+// of the given kinds and is set.
+func (e OptionExtractor) getScalar(xt protoreflect.ExtensionType, kinds ...protoreflect.Kind) (protoreflect.Value, bool) {
+	xd := xt.TypeDescriptor()
+	if xd.IsList() {
+		return protoreflect.Value{}, false
+	}
+	for _, kind := range kinds {
+		if xd.Kind() == kind {
+			return e.Get(xt)
+		}
+	}
+	return protoreflect.Value{}, false
+}
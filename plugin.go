@@ -0,0 +1,173 @@
+package protogen
+
+import (
+	"errors"
+
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// DefaultPlugin provides default implementations of the Plugin methods
+// other than Generate. Plugins embed it and override only what they need:
+// it reports no supported features and no supported editions.
+type DefaultPlugin struct{}
+
+func (DefaultPlugin) SupportedFeatures() uint64 {
+	return 0
+}
+
+func (DefaultPlugin) SupportedEditionsMinimum() descriptorpb.Edition {
+	return descriptorpb.Edition_EDITION_UNKNOWN
+}
+
+func (DefaultPlugin) SupportedEditionsMaximum() descriptorpb.Edition {
+	return descriptorpb.Edition_EDITION_UNKNOWN
+}
+
+// NewPlugin returns a Plugin that generates each file by calling fn.
+// It reports no supported features and no supported editions.
+func NewPlugin(fn func(gen *Generator, file *File) error) Plugin {
+	return NewPluginWithFeatures(fn, 0, descriptorpb.Edition_EDITION_UNKNOWN, descriptorpb.Edition_EDITION_UNKNOWN)
+}
+
+// NewPluginWithFeatures returns a Plugin that generates each file by calling
+// fn and reports the given supported features and edition range.
+func NewPluginWithFeatures(fn func(gen *Generator, file *File) error, features uint64, minEdition, maxEdition descriptorpb.Edition) Plugin {
+	return &funcPlugin{
+		fn:         fn,
+		features:   features,
+		minEdition: minEdition,
+		maxEdition: maxEdition,
+	}
+}
+
+type funcPlugin struct {
+	fn         func(gen *Generator, file *File) error
+	features   uint64
+	minEdition descriptorpb.Edition
+	maxEdition descriptorpb.Edition
+}
+
+func (p *funcPlugin) Generate(gen *Generator, file *File) error {
+	return p.fn(gen, file)
+}
+
+func (p *funcPlugin) SupportedFeatures() uint64 {
+	return p.features
+}
+
+func (p *funcPlugin) SupportedEditionsMinimum() descriptorpb.Edition {
+	return p.minEdition
+}
+
+func (p *funcPlugin) SupportedEditionsMaximum() descriptorpb.Edition {
+	return p.maxEdition
+}
+
+// ComposePlugins returns a Plugin that runs primary followed by each of the
+// secondary plugins, in order, for every file being generated. Secondary
+// plugins share the same Generator, so they can read and extend the files
+// generated before them with Generator.GetGeneratedFile.
+//
+// The composed plugin reports primary's supported features and the
+// intersection of all edition ranges. If the ranges do not overlap, it
+// reports no supported editions and Generate fails.
+func ComposePlugins(primary Plugin, secondary ...Plugin) Plugin {
+	return &composedPlugin{
+		primary:   primary,
+		secondary: secondary,
+	}
+}
+
+type composedPlugin struct {
+	primary   Plugin
+	secondary []Plugin
+}
+
+func (p *composedPlugin) Generate(gen *Generator, file *File) error {
+	if _, _, ok := p.editions(); !ok {
+		return errors.New("composed plugins support no common edition")
+	}
+
+	if err := p.primary.Generate(gen, file); err != nil {
+		return err
+	}
+
+	for _, s := range p.secondary {
+		if err := s.Generate(gen, file); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *composedPlugin) SupportedFeatures() uint64 {
+	return p.primary.SupportedFeatures()
+}
+
+func (p *composedPlugin) SupportedEditionsMinimum() descriptorpb.Edition {
+	minimum, _, _ := p.editions()
+	return minimum
+}
+
+func (p *composedPlugin) SupportedEditionsMaximum() descriptorpb.Edition {
+	_, maximum, _ := p.editions()
+	return maximum
+}
+
+// editions returns the intersection of the plugins' edition ranges. If any
+// plugin supports no editions, the result is EDITION_UNKNOWN for both
+// bounds. It reports false, with EDITION_UNKNOWN bounds, if the ranges do
+// not overlap.
+func (p *composedPlugin) editions() (minimum, maximum descriptorpb.Edition, ok bool) {
+	minimum = p.primary.SupportedEditionsMinimum()
+	maximum = p.primary.SupportedEditionsMaximum()
+	for _, s := range p.secondary {
+		lo, hi := s.SupportedEditionsMinimum(), s.SupportedEditionsMaximum()
+		if minimum == descriptorpb.Edition_EDITION_UNKNOWN || lo == descriptorpb.Edition_EDITION_UNKNOWN {
+			minimum = descriptorpb.Edition_EDITION_UNKNOWN
+		} else if lo > minimum {
+			minimum = lo
+		}
+		if maximum == descriptorpb.Edition_EDITION_UNKNOWN || hi == descriptorpb.Edition_EDITION_UNKNOWN {
+			maximum = descriptorpb.Edition_EDITION_UNKNOWN
+		} else if hi < maximum {
+			maximum = hi
+		}
+	}
+
+	if minimum != descriptorpb.Edition_EDITION_UNKNOWN && maximum != descriptorpb.Edition_EDITION_UNKNOWN && minimum > maximum {
+		return descriptorpb.Edition_EDITION_UNKNOWN, descriptorpb.Edition_EDITION_UNKNOWN, false
+	}
+	return minimum, maximum, true
+}
+
+// FeatureFlags is a structured form of the bitmask returned by
+// Plugin.SupportedFeatures. A plugin can implement SupportedFeatures as
+//
+//	return protogen.FeatureFlags{Proto3Optional: true}.ToUint64()
+type FeatureFlags struct {
+	Proto3Optional   bool // FEATURE_PROTO3_OPTIONAL
+	SupportsEditions bool // FEATURE_SUPPORTS_EDITIONS
+}
+
+// FeatureFlagsFromUint64 parses a CodeGeneratorResponse feature bitmask.
+// Unknown bits are ignored.
+func FeatureFlagsFromUint64(n uint64) FeatureFlags {
+	return FeatureFlags{
+		Proto3Optional:   n&uint64(pluginpb.CodeGeneratorResponse_FEATURE_PROTO3_OPTIONAL) != 0,
+		SupportsEditions: n&uint64(pluginpb.CodeGeneratorResponse_FEATURE_SUPPORTS_EDITIONS) != 0,
+	}
+}
+
+// ToUint64 returns the flags as a CodeGeneratorResponse feature bitmask.
+func (f FeatureFlags) ToUint64() uint64 {
+	var n uint64
+	if f.Proto3Optional {
+		n |= uint64(pluginpb.CodeGeneratorResponse_FEATURE_PROTO3_OPTIONAL)
+	}
+	if f.SupportsEditions {
+		n |= uint64(pluginpb.CodeGeneratorResponse_FEATURE_SUPPORTS_EDITIONS)
+	}
+	return n
+}
@@ -2,9 +2,18 @@ package protogen
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"path"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
 
 	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
 	"google.golang.org/protobuf/reflect/protoreflect"
 	"google.golang.org/protobuf/reflect/protoregistry"
 	"google.golang.org/protobuf/types/descriptorpb"
@@ -30,30 +39,67 @@ type Generator struct {
 	filesByPath    map[string]*File
 	enumsByName    map[protoreflect.FullName]*Enum
 	messagesByName map[protoreflect.FullName]*Message
+	params         map[string]string
+	extensions     []protoreflect.ExtensionType
+	concurrency    int
+	onError        func(error)
 
-	genFiles []*GeneratedFile
-	err      error
+	out  *output // shared with the per-call views made by GenerateFiles
+	call int     // 1 + index of the file a view generates; 0 if not a view
+}
+
+// output holds the results of generation.
+type output struct {
+	mu     sync.Mutex // guards the fields below during parallel generation
+	files  []*GeneratedFile
+	err    error
+	halted bool
 }
 
 func NewGenerator(req *pluginpb.CodeGeneratorRequest, plugin Plugin) (*Generator, error) {
+	return NewGeneratorWithFileReg(req, plugin, new(protoregistry.Files))
+}
+
+// NewGeneratorWithFileReg is like NewGenerator but loads the files of the
+// request into reg, a registry that may already be populated, e.g. by an
+// earlier descriptor loader. Imports missing from the request are loaded
+// from reg, as files that are not generated. A request file already present
+// in reg is not registered again, but it is an error if the registered file
+// differs from it.
+func NewGeneratorWithFileReg(req *pluginpb.CodeGeneratorRequest, plugin Plugin, reg *protoregistry.Files) (*Generator, error) {
 	gen := &Generator{
 		request:        req,
 		plugin:         plugin,
-		fileReg:        new(protoregistry.Files),
+		fileReg:        reg,
 		filesByPath:    make(map[string]*File),
 		enumsByName:    make(map[protoreflect.FullName]*Enum),
 		messagesByName: make(map[protoreflect.FullName]*Message),
+		params:         parseParameters(req.GetParameter()),
+		out:            new(output),
 	}
 
+	if err := gen.loadFiles(); err != nil {
+		return nil, err
+	}
+	return gen, nil
+}
+
+func (gen *Generator) loadFiles() error {
 	for _, protoFile := range gen.request.ProtoFile {
 		filename := protoFile.GetName()
 		if gen.filesByPath[filename] != nil {
-			return nil, fmt.Errorf("duplicate file name: %q", filename)
+			return fmt.Errorf("duplicate file name: %q", filename)
+		}
+
+		for _, dep := range protoFile.GetDependency() {
+			if err := gen.loadRegisteredFile(dep); err != nil {
+				return err
+			}
 		}
 
 		f, err := newFile(gen, protoFile)
 		if err != nil {
-			return nil, err
+			return err
 		}
 
 		gen.files = append(gen.files, f)
@@ -63,28 +109,294 @@ func NewGenerator(req *pluginpb.CodeGeneratorRequest, plugin Plugin) (*Generator
 	for _, filename := range gen.request.FileToGenerate {
 		f, ok := gen.filesByPath[filename]
 		if !ok {
-			return nil, fmt.Errorf("no descriptor for generated file: %v", filename)
+			return fmt.Errorf("no descriptor for generated file: %v", filename)
 		}
 		f.Generate = true
 	}
 
-	return gen, nil
+	return nil
+}
+
+// loadRegisteredFile loads the file named filename, and its imports, from
+// the registry unless it has already been loaded. A file missing from the
+// registry is left for newFile to report as an unresolvable import.
+func (gen *Generator) loadRegisteredFile(filename string) error {
+	if gen.filesByPath[filename] != nil {
+		return nil
+	}
+	desc, err := gen.fileReg.FindFileByPath(filename)
+	if err != nil {
+		return nil
+	}
+
+	protoFile := protodesc.ToFileDescriptorProto(desc)
+	for _, dep := range protoFile.GetDependency() {
+		if err := gen.loadRegisteredFile(dep); err != nil {
+			return err
+		}
+	}
+
+	f, err := newFile(gen, protoFile)
+	if err != nil {
+		return err
+	}
+	gen.files = append(gen.files, f)
+	gen.filesByPath[filename] = f
+	return nil
+}
+
+// RequestFromDescriptorSet builds a CodeGeneratorRequest from a
+// FileDescriptorSet, such as one produced by protoc --descriptor_set_out.
+// The files in fds must be topologically ordered, as protoc emits them
+// with --include_imports.
+func RequestFromDescriptorSet(fds *descriptorpb.FileDescriptorSet, toGenerate []string, parameter string) *pluginpb.CodeGeneratorRequest {
+	req := &pluginpb.CodeGeneratorRequest{
+		FileToGenerate: toGenerate,
+		ProtoFile:      fds.GetFile(),
+	}
+	if parameter != "" {
+		req.Parameter = proto.String(parameter)
+	}
+	return req
+}
+
+// FileToGenerateList returns the names of the files to generate, exactly
+// as listed in the request.
+func (gen *Generator) FileToGenerateList() []string {
+	return gen.request.GetFileToGenerate()
+}
+
+// GetFileByPath returns the file with the given .proto path, as named in
+// the request. The path is cleaned first, so "./a/../b.proto" finds "b.proto".
+func (gen *Generator) GetFileByPath(filename string) (*File, bool) {
+	f, ok := gen.filesByPath[path.Clean(filepath.ToSlash(filename))]
+	return f, ok
+}
+
+// GetFileForDescriptor returns the File wrapping desc, as found by its path.
+func (gen *Generator) GetFileForDescriptor(desc protoreflect.FileDescriptor) (*File, bool) {
+	f, ok := gen.filesByPath[desc.Path()]
+	return f, ok
+}
+
+// AllExtensions returns every extension declared in any file, both at file
+// scope and nested within messages. Extensions are ordered by file, then by
+// declaration, with a file's top-level extensions first. Use
+// [Field.GetFile] to find the declaring file.
+func (gen *Generator) AllExtensions() []*Extension {
+	var extensions []*Extension
+	var walk func(messages []*Message)
+	walk = func(messages []*Message) {
+		for _, message := range messages {
+			extensions = append(extensions, message.Extensions...)
+			walk(message.Messages)
+		}
+	}
+
+	for _, f := range gen.files {
+		extensions = append(extensions, f.Extensions...)
+		walk(f.Messages)
+	}
+	return extensions
+}
+
+// DescribeFiles returns a human-readable summary of the loaded files, one
+// per line, for debugging which files are generated.
+func (gen *Generator) DescribeFiles() string {
+	var b strings.Builder
+	for _, f := range gen.files {
+		fmt.Fprintf(&b, "%s: package %q, %d messages, %d services, generate=%v\n",
+			f.GetSourcePath(), f.GetPackage(), len(f.Messages), len(f.Services), f.Generate)
+	}
+	return b.String()
+}
+
+// RegisterExtension makes the custom option xt known to the generator,
+// for helpers that interpret well-known custom options such as
+// Message.IsAbstract.
+func (gen *Generator) RegisterExtension(xt protoreflect.ExtensionType) {
+	gen.extensions = append(gen.extensions, xt)
+}
+
+// SetPlugin replaces the plugin used by the generator.
+// It has no effect on files already generated, so it should be called
+// before GenerateFiles.
+func (gen *Generator) SetPlugin(plugin Plugin) {
+	if plugin == nil {
+		panic("protogen: SetPlugin called with nil plugin")
+	}
+	gen.plugin = plugin
+}
+
+// MaxConcurrency sets the number of files GenerateFiles may generate in
+// parallel and returns gen for chaining. A value below 1 means
+// runtime.NumCPU(). With a value above 1, the plugin's Generate method must
+// be safe for concurrent use and generated files may be created in any order.
+//
+// During parallel generation, Generate may call the NewGeneratedFile
+// methods, Panic and the read-only accessors of gen, such as Parameters,
+// GetFileByPath and ProtocVersion. Methods that configure or reset gen,
+// such as SetPlugin, WithRequestParameter and ResetGeneratedFiles, must not
+// be called concurrently with GenerateFiles.
+func (gen *Generator) MaxConcurrency(n int) *Generator {
+	if n < 1 {
+		n = runtime.NumCPU()
+	}
+	gen.concurrency = n
+	return gen
 }
 
 func (gen *Generator) GenerateFiles() {
-	for _, file := range gen.files {
+	if gen.concurrency > 1 {
+		gen.generateFilesParallel()
+		return
+	}
+
+	for i, file := range gen.files {
 		if !file.Generate {
 			continue
 		}
+		if gen.isHalted() {
+			return
+		}
 
-		err := gen.plugin.Generate(gen, file)
+		err := gen.generateFile(i, file)
 		if err != nil {
-			gen.err = err
+			if gen.onError != nil {
+				gen.onError(err)
+				continue
+			}
+			gen.setErr(err.Err)
 			return
 		}
 	}
 }
 
+func (gen *Generator) generateFilesParallel() {
+	errs := make([]*FileError, len(gen.files))
+	indexes := make(chan int)
+
+	var wg sync.WaitGroup
+	for i := 0; i < gen.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				if !gen.isHalted() {
+					errs[i] = gen.generateFile(i, gen.files[i])
+				}
+			}
+		}()
+	}
+
+	for i, file := range gen.files {
+		if file.Generate {
+			indexes <- i
+		}
+	}
+	close(indexes)
+	wg.Wait()
+
+	// Report the error of the earliest file, as sequential generation would.
+	for _, err := range errs {
+		if err == nil {
+			continue
+		}
+		if gen.onError != nil {
+			gen.onError(err)
+			continue
+		}
+		gen.setErr(err.Err)
+		return
+	}
+}
+
+// generateFile runs the plugin on file, the i'th file of gen. The plugin is
+// given a view of gen that tags the files it creates, so that if OnError is
+// set and the plugin fails, the files of the failed call can be discarded.
+func (gen *Generator) generateFile(i int, file *File) *FileError {
+	view := *gen
+	view.call = i + 1
+	err := gen.plugin.Generate(&view, file)
+	if err == nil {
+		return nil
+	}
+
+	if gen.onError != nil {
+		gen.out.mu.Lock()
+		files := gen.out.files[:0]
+		for _, g := range gen.out.files {
+			if g.call != view.call {
+				files = append(files, g)
+			}
+		}
+		gen.out.files = files
+		gen.out.mu.Unlock()
+	}
+	return &FileError{File: file, Err: err}
+}
+
+// A FileError is an error returned by the plugin's Generate method.
+type FileError struct {
+	File *File // file being generated
+	Err  error
+}
+
+func (e *FileError) Error() string {
+	return e.File.Desc.Path() + ": " + e.Err.Error()
+}
+
+func (e *FileError) Unwrap() error {
+	return e.Err
+}
+
+// OnError registers fn to be called with each error returned by the
+// plugin's Generate method, as a *FileError. Such errors are then not
+// reported by Response, any files created by the failed call are discarded,
+// and generation continues with the next file. fn is never called
+// concurrently. Errors recorded by Panic still halt generation.
+func (gen *Generator) OnError(fn func(error)) {
+	gen.onError = fn
+}
+
+// Panic records an error describing a programming error in the plugin and
+// halts generation: GenerateFiles generates no further files, and Response
+// reports this error in preference to any other. Unlike a real panic, it
+// returns normally, so the plugin should return from Generate after calling it.
+func (gen *Generator) Panic(format string, args ...any) {
+	gen.out.mu.Lock()
+	defer gen.out.mu.Unlock()
+	if !gen.out.halted {
+		gen.out.halted = true
+		gen.out.err = fmt.Errorf(format, args...)
+	}
+}
+
+func (gen *Generator) isHalted() bool {
+	gen.out.mu.Lock()
+	defer gen.out.mu.Unlock()
+	return gen.out.halted
+}
+
+// setErr records err unless generation has been halted by Panic.
+func (gen *Generator) setErr(err error) {
+	gen.out.mu.Lock()
+	defer gen.out.mu.Unlock()
+	if !gen.out.halted {
+		gen.out.err = err
+	}
+}
+
+// ResetGeneratedFiles discards all generated files and any error recorded
+// by GenerateFiles or Panic, so that generation can be run again from scratch.
+func (gen *Generator) ResetGeneratedFiles() {
+	gen.out.mu.Lock()
+	defer gen.out.mu.Unlock()
+	gen.out.files = nil
+	gen.out.err = nil
+	gen.out.halted = false
+}
+
 func (gen *Generator) ProtocVersion() string {
 	v := gen.request.GetCompilerVersion()
 	if v == nil {
@@ -99,14 +411,68 @@ func (gen *Generator) ProtocVersion() string {
 	return fmt.Sprintf("v%d.%d.%d%s", v.GetMajor(), v.GetMinor(), v.GetPatch(), suffix)
 }
 
+// Parameters returns the key=value pairs of the comma-separated parameter
+// passed to the plugin. A key given without a value maps to "".
+// The map must not be modified.
+func (gen *Generator) Parameters() map[string]string {
+	return gen.params
+}
+
+func parseParameters(parameter string) map[string]string {
+	params := make(map[string]string)
+	for _, param := range strings.Split(parameter, ",") {
+		if param == "" {
+			continue
+		}
+		key, value, _ := strings.Cut(param, "=")
+		params[key] = value
+	}
+	return params
+}
+
+// WithRequestParameter sets the parameter key to value, as if it had been
+// passed to the plugin, and returns gen for chaining. It must be called
+// before GenerateFiles.
+func (gen *Generator) WithRequestParameter(key, value string) *Generator {
+	gen.params[key] = value
+	return gen
+}
+
+// WarnUnusedParameters returns an error naming every parameter key that is
+// not among accessed, so that misspelled parameters are not silently ignored.
+func (gen *Generator) WarnUnusedParameters(accessed ...string) error {
+	known := make(map[string]bool, len(accessed))
+	for _, key := range accessed {
+		known[key] = true
+	}
+
+	var unknown []string
+	for key := range gen.Parameters() {
+		if !known[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+
+	sort.Strings(unknown)
+	return fmt.Errorf("unknown parameter: %s", strings.Join(unknown, ", "))
+}
+
 func (gen *Generator) Response() *pluginpb.CodeGeneratorResponse {
 	resp := &pluginpb.CodeGeneratorResponse{}
-	if gen.err != nil {
-		resp.Error = proto.String(gen.err.Error())
+	if gen.out.err != nil {
+		resp.Error = proto.String(gen.out.err.Error())
 		return resp
 	}
 
-	for _, g := range gen.genFiles {
+	for _, g := range gen.out.files {
+		if g.merged != nil {
+			resp.File = append(resp.File, g.merged)
+			continue
+		}
+
 		content, err := g.Content()
 		if err != nil {
 			return &pluginpb.CodeGeneratorResponse{
@@ -114,10 +480,18 @@ func (gen *Generator) Response() *pluginpb.CodeGeneratorResponse {
 			}
 		}
 
+		info, err := g.generatedCodeInfo()
+		if err != nil {
+			return &pluginpb.CodeGeneratorResponse{
+				Error: proto.String(err.Error()),
+			}
+		}
+
 		filename := g.filename
 		resp.File = append(resp.File, &pluginpb.CodeGeneratorResponse_File{
-			Name:    proto.String(filename),
-			Content: proto.String(string(content)),
+			Name:              proto.String(filename),
+			Content:           proto.String(string(content)),
+			GeneratedCodeInfo: info,
 		})
 	}
 
@@ -137,22 +511,159 @@ func (gen *Generator) Response() *pluginpb.CodeGeneratorResponse {
 	return resp
 }
 
+// RunPlugin runs gen's plugin on req with a new Generator and returns its
+// response, which may be added to gen with MergeResponse. Errors, including
+// an invalid req, are reported in the response rather than in gen.
+func (gen *Generator) RunPlugin(req *pluginpb.CodeGeneratorRequest) *pluginpb.CodeGeneratorResponse {
+	return runPlugin(req, gen.plugin)
+}
+
+// MergeResponse adds the files of a sub-plugin's response to the files
+// generated by gen. They are reported by Response as they are, including
+// any insertion point and generated code info. If resp reports an error, it
+// is returned and no files are added. A nil resp adds nothing.
+func (gen *Generator) MergeResponse(resp *pluginpb.CodeGeneratorResponse) error {
+	if resp.GetError() != "" {
+		return errors.New(resp.GetError())
+	}
+
+	for _, f := range resp.GetFile() {
+		g := gen.NewGeneratedFileBinary(f.GetName())
+		g.buf.WriteString(f.GetContent())
+		g.merged = proto.Clone(f).(*pluginpb.CodeGeneratorResponse_File)
+	}
+	return nil
+}
+
 type GeneratedFile struct {
 	gen      *Generator
 	filename string
+	header   bytes.Buffer
 	buf      bytes.Buffer
+	binary   bool
+	indent   string // indentation unit for P2; "\t" if empty
+	imports  *ImportManager
+
+	sections       []*GeneratedFile
+	sectionsByName map[string]*GeneratedFile
+	fences         map[string]bool
+
+	annotations []annotation
+
+	merged *pluginpb.CodeGeneratorResponse_File // file added by MergeResponse, reported as is
+
+	call int // Generator.call of the generator that created the file
 }
 
 func (gen *Generator) NewGeneratedFile(filename string) *GeneratedFile {
 	g := &GeneratedFile{
 		gen:      gen,
 		filename: filename,
+		call:     gen.call,
 	}
 
-	gen.genFiles = append(gen.genFiles, g)
+	gen.out.mu.Lock()
+	gen.out.files = append(gen.out.files, g)
+	gen.out.mu.Unlock()
 	return g
 }
 
+// GetGeneratedFile returns the first generated file named filename, so that
+// a plugin can read or extend the output of a plugin that ran before it.
+func (gen *Generator) GetGeneratedFile(filename string) (*GeneratedFile, bool) {
+	gen.out.mu.Lock()
+	defer gen.out.mu.Unlock()
+	for _, g := range gen.out.files {
+		if g.filename == filename {
+			return g, true
+		}
+	}
+	return nil, false
+}
+
+// NewGeneratedFileAt creates a new generated file named filename within dir.
+// The name is joined with '/' regardless of the OS, as protoc expects.
+// Since protoc requires names relative to the output directory, a leading
+// '/' in dir is dropped.
+func (gen *Generator) NewGeneratedFileAt(dir, filename string) *GeneratedFile {
+	name := path.Join(filepath.ToSlash(dir), filepath.ToSlash(filename))
+	return gen.NewGeneratedFile(strings.TrimLeft(name, "/"))
+}
+
+// NewGeneratedFileSameDir creates a new generated file next to sourceFile,
+// named after it with the .proto extension replaced by suffix, e.g.
+// "subdir/my.proto" with suffix "_generated.go" yields
+// "subdir/my_generated.go".
+func (gen *Generator) NewGeneratedFileSameDir(sourceFile *File, suffix string) *GeneratedFile {
+	return gen.NewGeneratedFile(strings.TrimSuffix(sourceFile.GetSourcePath(), ".proto") + suffix)
+}
+
+// NewGeneratedFileForService creates a new generated file for service next
+// to the file declaring it, e.g. service Greeter in "subdir/my.proto" with
+// suffix "grpc.pb.go" yields "subdir/my_Greeter_grpc.pb.go".
+func (gen *Generator) NewGeneratedFileForService(service *Service, suffix string) *GeneratedFile {
+	return gen.NewGeneratedFileSameDir(service.File, "_"+service.GetName()+"_"+suffix)
+}
+
+// NewGeneratedFileBinary creates a new generated file for binary content.
+// Its Content is exactly the bytes written to it; injected headers and any
+// other text processing are skipped.
+func (gen *Generator) NewGeneratedFileBinary(filename string) *GeneratedFile {
+	g := gen.NewGeneratedFile(filename)
+	g.binary = true
+	return g
+}
+
+// NewGeneratedFileFromProto creates a new binary generated file whose
+// content is the wire encoding of msg.
+func (gen *Generator) NewGeneratedFileFromProto(filename string, msg proto.Message) (*GeneratedFile, error) {
+	b, err := proto.Marshal(msg)
+	if err != nil {
+		return nil, fmt.Errorf("marshal %v: %v", filename, err)
+	}
+	g := gen.NewGeneratedFileBinary(filename)
+	g.Write(b)
+	return g, nil
+}
+
+// newGeneratedFileOnce is like NewGeneratedFile but reports false, creating
+// nothing, if a file named filename has already been generated. The check
+// and the creation are atomic, so concurrent callers create the file once.
+func (gen *Generator) newGeneratedFileOnce(filename string) (*GeneratedFile, bool) {
+	gen.out.mu.Lock()
+	defer gen.out.mu.Unlock()
+	for _, g := range gen.out.files {
+		if g.filename == filename {
+			return nil, false
+		}
+	}
+
+	g := &GeneratedFile{
+		gen:      gen,
+		filename: filename,
+		call:     gen.call,
+	}
+	gen.out.files = append(gen.out.files, g)
+	return g, true
+}
+
+// NewGeneratedFileWithFilenameTemplate creates a new generated file whose
+// name is the result of executing tmplStr as a text/template with file as
+// its data, e.g. `{{ .GetPackage }}/{{ .GetSourcePath }}.txt`.
+func (gen *Generator) NewGeneratedFileWithFilenameTemplate(tmplStr string, file *File) (*GeneratedFile, error) {
+	tmpl, err := template.New("filename").Parse(tmplStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid filename template %q: %v", tmplStr, err)
+	}
+
+	var filename strings.Builder
+	if err := tmpl.Execute(&filename, file); err != nil {
+		return nil, fmt.Errorf("cannot execute filename template %q: %v", tmplStr, err)
+	}
+
+	return gen.NewGeneratedFile(filename.String()), nil
+}
+
 func (g *GeneratedFile) P(v ...any) {
 	for _, x := range v {
 		fmt.Fprint(&g.buf, x)
@@ -160,10 +671,177 @@ func (g *GeneratedFile) P(v ...any) {
 	fmt.Fprintln(&g.buf)
 }
 
+// PIf is like P but writes nothing if condition is false.
+func (g *GeneratedFile) PIf(condition bool, v ...any) {
+	if condition {
+		g.P(v...)
+	}
+}
+
+// P2 is like P but prefixes the line with indent copies of the file's
+// indentation unit.
+func (g *GeneratedFile) P2(indent int, v ...any) {
+	unit := g.indent
+	if unit == "" {
+		unit = "\t"
+	}
+	if indent > 0 {
+		g.buf.WriteString(strings.Repeat(unit, indent))
+	}
+	g.P(v...)
+}
+
+// SetIndentUnit sets the indentation unit used by P2, such as "\t" (the
+// default) or four spaces.
+func (g *GeneratedFile) SetIndentUnit(unit string) {
+	g.indent = unit
+}
+
 func (g *GeneratedFile) Write(p []byte) (n int, err error) {
 	return g.buf.Write(p)
 }
 
+// Header emits the standard "Code generated ... DO NOT EDIT." comment
+// recognized by Go tooling, followed by a blank line. version is typically
+// gen.ProtocVersion().
+func (g *GeneratedFile) Header(version string) {
+	g.P("// Code generated by protogen ", version, ". DO NOT EDIT.")
+	g.P()
+}
+
+// Comment emits a // comment with the formatted text. Each line of
+// multi-line text is commented separately.
+func (g *GeneratedFile) Comment(format string, args ...any) {
+	text := strings.TrimSuffix(fmt.Sprintf(format, args...), "\n")
+	for _, line := range strings.Split(text, "\n") {
+		if line == "" {
+			g.P("//")
+			continue
+		}
+		g.P("// ", line)
+	}
+}
+
+// BlockComment emits text wrapped in /* */. Multi-line text is placed on
+// lines of its own between the delimiters. Any "*/" in text is broken up so
+// that it does not end the comment early.
+func (g *GeneratedFile) BlockComment(text string) {
+	text = strings.ReplaceAll(strings.TrimSuffix(text, "\n"), "*/", "* /")
+	if !strings.Contains(text, "\n") {
+		g.P("/* ", text, " */")
+		return
+	}
+	g.P("/*")
+	g.P(text)
+	g.P("*/")
+}
+
+// Fence writes content verbatim between "// BEGIN marker" and
+// "// END marker" lines, so that tools updating the file later can find
+// the block. Writing the same marker twice in a file is a programming error
+// and halts generation via Generator.Panic.
+func (g *GeneratedFile) Fence(marker string, content string) {
+	if g.fences[marker] {
+		g.gen.Panic("%v: duplicate fence marker %q", g.filename, marker)
+		return
+	}
+	if g.fences == nil {
+		g.fences = make(map[string]bool)
+	}
+	g.fences[marker] = true
+
+	g.P("// BEGIN ", marker)
+	if content != "" {
+		g.P(strings.TrimSuffix(content, "\n"))
+	}
+	g.P("// END ", marker)
+}
+
+// A GeneratedBlock is a reusable piece of generated code.
+type GeneratedBlock interface {
+	WriteTo(g *GeneratedFile)
+}
+
+// Emit writes each of blocks to the file in order.
+func (g *GeneratedFile) Emit(blocks ...GeneratedBlock) {
+	for _, b := range blocks {
+		b.WriteTo(g)
+	}
+}
+
+// A LineBlock is a GeneratedBlock consisting of a single line.
+type LineBlock string
+
+func (b LineBlock) WriteTo(g *GeneratedFile) {
+	g.P(string(b))
+}
+
+// A CommentBlock is a GeneratedBlock writing the comments preceding a
+// declaration: each leading detached comment followed by a blank line,
+// then the leading comment.
+type CommentBlock CommentSet
+
+func (b CommentBlock) WriteTo(g *GeneratedFile) {
+	for _, c := range b.LeadingDetached {
+		g.buf.WriteString(c.String())
+		g.P()
+	}
+	g.buf.WriteString(b.Leading.String())
+}
+
+// Imports returns the ImportManager for the file, creating it on first use.
+func (g *GeneratedFile) Imports() *ImportManager {
+	if g.imports == nil {
+		g.imports = new(ImportManager)
+	}
+	return g.imports
+}
+
+// InjectHeader prepends header to the file content, regardless of what has
+// already been written. A trailing newline is added if absent. Multiple
+// headers appear in the order they were injected.
+func (g *GeneratedFile) InjectHeader(header string) {
+	g.header.WriteString(header)
+	if !strings.HasSuffix(header, "\n") {
+		g.header.WriteByte('\n')
+	}
+}
+
+// Section returns the named section of g, creating it on first use.
+// A section is written to like a file; g's content is its own text followed
+// by the content of each section, in the order the sections were created.
+func (g *GeneratedFile) Section(name string) *GeneratedFile {
+	if s, ok := g.sectionsByName[name]; ok {
+		return s
+	}
+
+	s := &GeneratedFile{
+		gen:      g.gen,
+		filename: g.filename,
+	}
+	if g.sectionsByName == nil {
+		g.sectionsByName = make(map[string]*GeneratedFile)
+	}
+	g.sectionsByName[name] = s
+	g.sections = append(g.sections, s)
+	return s
+}
+
 func (g *GeneratedFile) Content() ([]byte, error) {
-	return g.buf.Bytes(), nil
+	if g.binary || (g.header.Len() == 0 && len(g.sections) == 0) {
+		return g.buf.Bytes(), nil
+	}
+
+	var content bytes.Buffer
+	g.writeContent(&content)
+	return content.Bytes(), nil
+}
+
+// writeContent writes the header, text and sections of g to b.
+func (g *GeneratedFile) writeContent(b *bytes.Buffer) {
+	b.Write(g.header.Bytes())
+	b.Write(g.buf.Bytes())
+	for _, s := range g.sections {
+		s.writeContent(b)
+	}
 }
@@ -0,0 +1,116 @@
+package protogen
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+const (
+	// maxRequestSize limits the size of a CodeGeneratorRequest accepted by
+	// PluginHandler.
+	maxRequestSize = 64 << 20
+
+	// readHeaderTimeout limits how long a client may take to send the
+	// request headers, so that slow clients cannot hold connections open.
+	readHeaderTimeout = 10 * time.Second
+
+	// shutdownTimeout limits how long ServePlugin waits for in-flight
+	// requests once its context is cancelled.
+	shutdownTimeout = 30 * time.Second
+)
+
+// ServePlugin serves plugin over HTTP on addr until ctx is cancelled.
+// Each POST request body must be a serialized CodeGeneratorRequest of at
+// most 64 MiB; the response body is the serialized CodeGeneratorResponse.
+// Clients must send the request headers within 10 seconds. Requests may be
+// handled concurrently, so plugin must be safe for concurrent use.
+//
+// When ctx is cancelled, the server stops accepting connections and waits
+// up to 30 seconds for in-flight requests to complete before ServePlugin
+// returns nil. If they take longer, their connections are closed and
+// ServePlugin returns the shutdown error.
+func ServePlugin(ctx context.Context, plugin Plugin, addr string) error {
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           PluginHandler(plugin),
+		ReadHeaderTimeout: readHeaderTimeout,
+	}
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			srv.Close()
+			return err
+		}
+		if err := <-errc; !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return nil
+	}
+}
+
+// PluginHandler returns the http.Handler used by ServePlugin.
+func PluginHandler(plugin Plugin) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestSize))
+		if err != nil {
+			var maxErr *http.MaxBytesError
+			if errors.As(err, &maxErr) {
+				http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+				return
+			}
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		req := &pluginpb.CodeGeneratorRequest{}
+		if err := proto.Unmarshal(body, req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		out, err := proto.Marshal(runPlugin(req, plugin))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/x-protobuf")
+		w.Write(out)
+	})
+}
+
+// runPlugin runs plugin on req and returns its response. Errors creating
+// the generator are reported in the response, as protoc expects.
+func runPlugin(req *pluginpb.CodeGeneratorRequest, plugin Plugin) *pluginpb.CodeGeneratorResponse {
+	gen, err := NewGenerator(req, plugin)
+	if err != nil {
+		return &pluginpb.CodeGeneratorResponse{
+			Error: proto.String(err.Error()),
+		}
+	}
+
+	gen.GenerateFiles()
+	return gen.Response()
+}
@@ -0,0 +1,54 @@
+package protogen
+
+import (
+	"google.golang.org/protobuf/encoding/prototext"
+)
+
+// TextFormatPlugin is a Plugin that writes the text format of each
+// generated file's FileDescriptorProto to "<name>.txtpb", which helps when
+// debugging descriptor issues. Note that the text format output is not
+// stable across protobuf releases.
+type TextFormatPlugin struct {
+	DefaultPlugin
+
+	// IncludeDependencies also writes the descriptors of the transitive
+	// dependencies of each generated file.
+	IncludeDependencies bool
+}
+
+// NewTextFormatPlugin returns a TextFormatPlugin that writes only the
+// descriptors of the files being generated.
+func NewTextFormatPlugin() Plugin {
+	return &TextFormatPlugin{}
+}
+
+func (p *TextFormatPlugin) Generate(gen *Generator, file *File) error {
+	p.writeDescriptor(gen, file)
+	if !p.IncludeDependencies {
+		return nil
+	}
+
+	var walk func(f *File)
+	walk = func(f *File) {
+		for i, imports := 0, f.Desc.Imports(); i < imports.Len(); i++ {
+			dep, ok := gen.filesByPath[imports.Get(i).Path()]
+			if !ok || dep.Generate || !p.writeDescriptor(gen, dep) {
+				continue
+			}
+			walk(dep)
+		}
+	}
+	walk(file)
+	return nil
+}
+
+// writeDescriptor writes the descriptor of f unless it was already written,
+// and reports whether it wrote it.
+func (p *TextFormatPlugin) writeDescriptor(gen *Generator, f *File) bool {
+	g, ok := gen.newGeneratedFileOnce(f.GetSourcePath() + ".txtpb")
+	if !ok {
+		return false
+	}
+	g.P(prototext.Format(f.Proto))
+	return true
+}
@@ -1,11 +1,22 @@
 package protogen
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"go/token"
+	"path"
+	"sort"
 	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
 
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/reflect/protodesc"
 	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
 	"google.golang.org/protobuf/types/descriptorpb"
 )
 
@@ -21,6 +32,8 @@ type File struct {
 	Services   []*Service   // top-level service declarations
 
 	Generate bool // true if we should generate code for this file
+
+	gen *Generator
 }
 
 func newFile(gen *Generator, p *descriptorpb.FileDescriptorProto) (*File, error) {
@@ -29,13 +42,19 @@ func newFile(gen *Generator, p *descriptorpb.FileDescriptorProto) (*File, error)
 		return nil, fmt.Errorf("invalid FileDescriptorProto %q: %v", p.GetName(), err)
 	}
 
-	if err := gen.fileReg.RegisterFile(desc); err != nil {
+	// A pre-seeded registry may already hold the file.
+	if registered, err := gen.fileReg.FindFileByPath(p.GetName()); err == nil {
+		if !sameFile(registered, p) {
+			return nil, fmt.Errorf("descriptor %q differs from the registered file of the same name", p.GetName())
+		}
+	} else if err := gen.fileReg.RegisterFile(desc); err != nil {
 		return nil, fmt.Errorf("cannot register descriptor %q: %v", p.GetName(), err)
 	}
 
 	f := &File{
 		Proto: p,
 		Desc:  desc,
+		gen:   gen,
 	}
 
 	for i, eds := 0, desc.Enums(); i < eds.Len(); i++ {
@@ -77,18 +96,73 @@ func newFile(gen *Generator, p *descriptorpb.FileDescriptorProto) (*File, error)
 	return f, nil
 }
 
+// sameFile reports whether desc declares the same file as p, ignoring
+// source code info, which a registered descriptor often lacks.
+func sameFile(desc protoreflect.FileDescriptor, p *descriptorpb.FileDescriptorProto) bool {
+	a := protodesc.ToFileDescriptorProto(desc)
+	b := proto.Clone(p).(*descriptorpb.FileDescriptorProto)
+	a.SourceCodeInfo, b.SourceCodeInfo = nil, nil
+	return proto.Equal(a, b)
+}
+
+func (f *File) GetProto() *descriptorpb.FileDescriptorProto {
+	return f.Proto
+}
+
 func (f *File) GetSourcePath() string {
 	return f.Desc.Path()
 }
 
+// SourceLocationFor returns the source location of desc, which must be
+// declared in f.
+func (f *File) SourceLocationFor(desc protoreflect.Descriptor) protoreflect.SourceLocation {
+	return f.Desc.SourceLocations().ByDescriptor(desc)
+}
+
 func (f *File) GetSyntax() string {
 	return f.Proto.GetSyntax()
 }
 
+// GetSyntaxComment returns the leading comments of the syntax declaration.
+func (f *File) GetSyntaxComment() Comments {
+	loc := f.Desc.SourceLocations().ByPath(protoreflect.SourcePath{12}) // FileDescriptorProto.syntax
+	return MakeCommentSet(loc).Leading
+}
+
 func (f *File) GetPackage() string {
 	return f.Proto.GetPackage()
 }
 
+// GetNormalizedPackage returns a package name usable as a Go package
+// identifier: the last dot-separated component of the proto package,
+// e.g. "myapi" for "my.company.myapi". Files without a package yield "proto".
+// As with protoc-gen-go's GoSanitized, characters other than letters and
+// digits become '_', and a Go keyword or a name not starting with a letter
+// is prefixed with '_', so "google.type" yields "_type".
+func (f *File) GetNormalizedPackage() string {
+	pkg := f.Proto.GetPackage()
+	if pkg == "" {
+		return "proto"
+	}
+	return goSanitized(pkg[strings.LastIndexByte(pkg, '.')+1:])
+}
+
+// goSanitized converts s to a valid Go identifier.
+func goSanitized(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return '_'
+	}, s)
+
+	r, _ := utf8.DecodeRuneInString(s)
+	if token.Lookup(s).IsKeyword() || !unicode.IsLetter(r) {
+		return "_" + s
+	}
+	return s
+}
+
 func (f *File) GetJavaPackage() string {
 	javaPackage := f.Proto.GetOptions().GetJavaPackage()
 	if len(javaPackage) == 0 {
@@ -97,13 +171,266 @@ func (f *File) GetJavaPackage() string {
 	return javaPackage
 }
 
+// GetJavaOuterClassName returns the java_outer_classname option if set.
+// Otherwise, as protoc does, it derives the name from the file's base name
+// in UpperCamelCase ("my_proto.proto" becomes "MyProto"), appending
+// "OuterClass" if that conflicts with the name of a service or of any
+// message or enum, including nested ones.
+func (f *File) GetJavaOuterClassName() string {
+	return javaOuterClassName(f.Desc)
+}
+
+func javaOuterClassName(fd protoreflect.FileDescriptor) string {
+	if name := fd.Options().(*descriptorpb.FileOptions).GetJavaOuterClassname(); name != "" {
+		return name
+	}
+
+	name := protoreflect.Name(javaCamelCase(strings.TrimSuffix(path.Base(fd.Path()), ".proto")))
+	if fd.Services().ByName(name) != nil || javaNameConflicts(fd.Enums(), fd.Messages(), name) {
+		return string(name) + "OuterClass"
+	}
+	return string(name)
+}
+
+// javaNameConflicts reports whether any of enums or messages, or of the
+// enums and messages nested within messages, is named name.
+func javaNameConflicts(enums protoreflect.EnumDescriptors, messages protoreflect.MessageDescriptors, name protoreflect.Name) bool {
+	if enums.ByName(name) != nil || messages.ByName(name) != nil {
+		return true
+	}
+	for i := 0; i < messages.Len(); i++ {
+		md := messages.Get(i)
+		if javaNameConflicts(md.Enums(), md.Messages(), name) {
+			return true
+		}
+	}
+	return false
+}
+
+// javaCamelCase converts s to UpperCamelCase following protoc's Java
+// generator: non-alphanumeric characters are dropped and the letter
+// following one, or following a digit, is capitalized.
+func javaCamelCase(s string) string {
+	var b []byte
+	capNext := true
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'a' <= c && c <= 'z':
+			if capNext {
+				c -= 'a' - 'A'
+			}
+			b = append(b, c)
+			capNext = false
+		case 'A' <= c && c <= 'Z':
+			b = append(b, c)
+			capNext = false
+		case '0' <= c && c <= '9':
+			b = append(b, c)
+			capNext = true
+		default:
+			capNext = true
+		}
+	}
+	return string(b)
+}
+
 func (f *File) GetDeprecated() bool {
 	return f.Proto.GetOptions().GetDeprecated()
 }
 
+// GetHashCode returns the hex-encoded SHA-256 hash of the deterministic
+// serialization of the file's descriptor proto, for cache invalidation.
+func (f *File) GetHashCode() string {
+	return hashProto(f.Proto)
+}
+
+func (f *File) GetEnums() []*Enum {
+	return f.Enums
+}
+
+func (f *File) GetMessages() []*Message {
+	return f.Messages
+}
+
+// GetExtensionProtos returns the FieldDescriptorProtos of the top-level
+// extensions.
+func (f *File) GetExtensionProtos() []*descriptorpb.FieldDescriptorProto {
+	return f.Proto.GetExtension()
+}
+
+// HasMessage reports whether the file declares a top-level message
+// with the given name.
+func (f *File) HasMessage(name string) bool {
+	for _, message := range f.Messages {
+		if message.GetName() == name {
+			return true
+		}
+	}
+	return false
+}
+
+// HasService reports whether the file declares a service with the given name.
+func (f *File) HasService(name string) bool {
+	for _, service := range f.Services {
+		if service.GetName() == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *File) GetEnumCount() int {
+	return len(f.Enums)
+}
+
+func (f *File) GetMessageCount() int {
+	return len(f.Messages)
+}
+
+func (f *File) GetExtensionCount() int {
+	return len(f.Extensions)
+}
+
+func (f *File) GetServiceCount() int {
+	return len(f.Services)
+}
+
+// GetEnumNames returns the names of the top-level enums.
+func (f *File) GetEnumNames() []string {
+	names := make([]string, len(f.Enums))
+	for i, enum := range f.Enums {
+		names[i] = string(enum.Desc.Name())
+	}
+	return names
+}
+
+// GetMessageNames returns the names of the top-level messages.
+func (f *File) GetMessageNames() []string {
+	names := make([]string, len(f.Messages))
+	for i, message := range f.Messages {
+		names[i] = message.GetName()
+	}
+	return names
+}
+
+// GetServiceNames returns the names of the services.
+func (f *File) GetServiceNames() []string {
+	names := make([]string, len(f.Services))
+	for i, service := range f.Services {
+		names[i] = service.GetName()
+	}
+	return names
+}
+
+// FilterMessages returns the top-level messages for which predicate
+// returns true.
+func (f *File) FilterMessages(predicate func(*Message) bool) []*Message {
+	var messages []*Message
+	for _, message := range f.Messages {
+		if predicate(message) {
+			messages = append(messages, message)
+		}
+	}
+	return messages
+}
+
+// FilterServices returns the services for which predicate returns true.
+func (f *File) FilterServices(predicate func(*Service) bool) []*Service {
+	var services []*Service
+	for _, service := range f.Services {
+		if predicate(service) {
+			services = append(services, service)
+		}
+	}
+	return services
+}
+
+// wellKnownGoImports maps the well-known .proto files to the Go packages
+// generated for them.
+var wellKnownGoImports = map[string]string{
+	"google/protobuf/any.proto":             "google.golang.org/protobuf/types/known/anypb",
+	"google/protobuf/api.proto":             "google.golang.org/protobuf/types/known/apipb",
+	"google/protobuf/duration.proto":        "google.golang.org/protobuf/types/known/durationpb",
+	"google/protobuf/empty.proto":           "google.golang.org/protobuf/types/known/emptypb",
+	"google/protobuf/field_mask.proto":      "google.golang.org/protobuf/types/known/fieldmaskpb",
+	"google/protobuf/source_context.proto":  "google.golang.org/protobuf/types/known/sourcecontextpb",
+	"google/protobuf/struct.proto":          "google.golang.org/protobuf/types/known/structpb",
+	"google/protobuf/timestamp.proto":       "google.golang.org/protobuf/types/known/timestamppb",
+	"google/protobuf/type.proto":            "google.golang.org/protobuf/types/known/typepb",
+	"google/protobuf/wrappers.proto":        "google.golang.org/protobuf/types/known/wrapperspb",
+	"google/protobuf/descriptor.proto":      "google.golang.org/protobuf/types/descriptorpb",
+	"google/protobuf/compiler/plugin.proto": "google.golang.org/protobuf/types/pluginpb",
+}
+
+// GetGoImports returns the sorted import paths a Go file generated for f
+// would typically need: the protobuf runtime packages, plus the packages of
+// message and enum types referenced from other files. Well-known types map
+// to their standard packages; other files use their go_package option and
+// are omitted if it is unset.
+func (f *File) GetGoImports() []string {
+	seen := make(map[string]bool)
+	if len(f.Messages) > 0 || len(f.Enums) > 0 || len(f.Extensions) > 0 {
+		seen["google.golang.org/protobuf/reflect/protoreflect"] = true
+		seen["google.golang.org/protobuf/runtime/protoimpl"] = true
+	}
+
+	use := func(fd protoreflect.FileDescriptor) {
+		if fd.Path() == f.Desc.Path() {
+			return
+		}
+		if importPath, ok := wellKnownGoImports[fd.Path()]; ok {
+			seen[importPath] = true
+			return
+		}
+		goPackage := fd.Options().(*descriptorpb.FileOptions).GetGoPackage()
+		if importPath, _, _ := strings.Cut(goPackage, ";"); importPath != "" {
+			seen[importPath] = true
+		}
+	}
+	useField := func(field *Field) {
+		if field.Desc.IsExtension() {
+			use(field.Desc.ContainingMessage().ParentFile())
+		}
+		switch {
+		case field.Desc.Enum() != nil:
+			use(field.Desc.Enum().ParentFile())
+		case field.Desc.Message() != nil:
+			use(field.Desc.Message().ParentFile())
+		}
+	}
+
+	var walk func(messages []*Message)
+	walk = func(messages []*Message) {
+		for _, message := range messages {
+			for _, field := range message.Fields {
+				useField(field)
+			}
+			for _, extension := range message.Extensions {
+				useField(extension)
+			}
+			walk(message.Messages)
+		}
+	}
+	walk(f.Messages)
+	for _, extension := range f.Extensions {
+		useField(extension)
+	}
+
+	imports := make([]string, 0, len(seen))
+	for importPath := range seen {
+		imports = append(imports, importPath)
+	}
+	sort.Strings(imports)
+	return imports
+}
+
 // An Enum describes an enum.
 type Enum struct {
 	Desc protoreflect.EnumDescriptor
+	File *File // file in which this enum is declared
+
+	Parent *Message // message in which this enum is declared; nil if top-level
 
 	Values []*EnumValue // enum value declarations
 
@@ -113,7 +440,9 @@ type Enum struct {
 func newEnum(gen *Generator, f *File, parent *Message, desc protoreflect.EnumDescriptor) *Enum {
 	enum := &Enum{
 		Desc:     desc,
-		Comments: MakeCommentSet(f.Desc.SourceLocations().ByDescriptor(desc)),
+		Parent:   parent,
+		Comments: MakeCommentSet(f.SourceLocationFor(desc)),
+		File:     f,
 	}
 	gen.enumsByName[desc.FullName()] = enum
 
@@ -124,9 +453,47 @@ func newEnum(gen *Generator, f *File, parent *Message, desc protoreflect.EnumDes
 	return enum
 }
 
+// GetFile returns the file in which the enum is declared.
+func (enum *Enum) GetFile() *File {
+	return enum.File
+}
+
+func (enum *Enum) GetValueCount() int {
+	return len(enum.Values)
+}
+
+// IsEmpty reports whether the enum declares no values.
+func (enum *Enum) IsEmpty() bool {
+	return len(enum.Values) == 0
+}
+
+func (enum *Enum) GetParent() *Message {
+	return enum.Parent
+}
+
+func (enum *Enum) GetIndex() int {
+	return enum.Desc.Index()
+}
+
+// GetEnumOptions returns the enum's options, or empty options if none
+// are set.
+func (enum *Enum) GetEnumOptions() *descriptorpb.EnumOptions {
+	options, _ := enum.Desc.Options().(*descriptorpb.EnumOptions)
+	if options == nil {
+		return &descriptorpb.EnumOptions{}
+	}
+	return options
+}
+
+// GetProtoFilePath returns the path of the .proto file declaring the enum.
+func (enum *Enum) GetProtoFilePath() string {
+	return enum.Desc.ParentFile().Path()
+}
+
 // An EnumValue describes an enum value.
 type EnumValue struct {
 	Desc protoreflect.EnumValueDescriptor
+	File *File // file in which this enum value is declared
 
 	Parent *Enum // enum in which this value is declared
 
@@ -136,14 +503,32 @@ type EnumValue struct {
 func newEnumValue(gen *Generator, f *File, message *Message, enum *Enum, desc protoreflect.EnumValueDescriptor) *EnumValue {
 	return &EnumValue{
 		Desc:     desc,
+		File:     f,
 		Parent:   enum,
-		Comments: MakeCommentSet(f.Desc.SourceLocations().ByDescriptor(desc)),
+		Comments: MakeCommentSet(f.SourceLocationFor(desc)),
 	}
 }
 
+// GetFile returns the file in which the enum value is declared.
+func (ev *EnumValue) GetFile() *File {
+	return ev.File
+}
+
+func (ev *EnumValue) GetIndex() int {
+	return ev.Desc.Index()
+}
+
+// GetParentMessage returns the message enclosing the value's enum,
+// reporting false if the enum is declared at file scope.
+func (ev *EnumValue) GetParentMessage() (*Message, bool) {
+	message := ev.Parent.GetParent()
+	return message, message != nil
+}
+
 // A Message describes a message.
 type Message struct {
 	Desc protoreflect.MessageDescriptor
+	File *File // file in which this message is declared
 
 	Fields []*Field // message field declarations
 	Oneofs []*Oneof // message oneof declarations
@@ -153,12 +538,23 @@ type Message struct {
 	Extensions []*Extension // nested extension declarations
 
 	Comments CommentSet // comments associated with this message
+
+	proto *descriptorpb.DescriptorProto
+
+	fieldsByNumberOnce sync.Once
+	fieldsByNumber     map[protoreflect.FieldNumber]*Field
 }
 
 func newMessage(gen *Generator, f *File, parent *Message, desc protoreflect.MessageDescriptor) *Message {
 	message := &Message{
 		Desc:     desc,
-		Comments: MakeCommentSet(f.Desc.SourceLocations().ByDescriptor(desc)),
+		Comments: MakeCommentSet(f.SourceLocationFor(desc)),
+		File:     f,
+	}
+	if parent == nil {
+		message.proto = f.Proto.GetMessageType()[desc.Index()]
+	} else {
+		message.proto = parent.proto.GetNestedType()[desc.Index()]
 	}
 	gen.messagesByName[desc.FullName()] = message
 
@@ -220,6 +616,176 @@ func (message *Message) GetName() string {
 	return string(message.Desc.Name())
 }
 
+// GetFile returns the file in which the message is declared.
+func (message *Message) GetFile() *File {
+	return message.File
+}
+
+// GetParentFile returns the file in which the message is declared,
+// however deeply the message is nested. It is equivalent to GetFile.
+func (message *Message) GetParentFile() *File {
+	return message.File
+}
+
+func (message *Message) GetFields() []*Field {
+	return message.Fields
+}
+
+// FindFieldByName returns the field with the given proto name.
+func (message *Message) FindFieldByName(name string) (*Field, bool) {
+	fd := message.Desc.Fields().ByName(protoreflect.Name(name))
+	if fd == nil {
+		return nil, false
+	}
+	return message.Fields[fd.Index()], true
+}
+
+// GetFieldByNumber returns the field with the given field number.
+// It is safe for concurrent use.
+func (message *Message) GetFieldByNumber(n protoreflect.FieldNumber) (*Field, bool) {
+	message.fieldsByNumberOnce.Do(func() {
+		message.fieldsByNumber = make(map[protoreflect.FieldNumber]*Field, len(message.Fields))
+		for _, field := range message.Fields {
+			message.fieldsByNumber[field.Desc.Number()] = field
+		}
+	})
+	field, ok := message.fieldsByNumber[n]
+	return field, ok
+}
+
+// GetFieldByJSONName returns the field with the given JSON name. As in the
+// proto3 JSON mapping, the field's proto name is also accepted.
+func (message *Message) GetFieldByJSONName(jsonName string) (*Field, bool) {
+	fds := message.Desc.Fields()
+	fd := fds.ByJSONName(jsonName)
+	if fd == nil {
+		fd = fds.ByName(protoreflect.Name(jsonName))
+	}
+	if fd == nil {
+		return nil, false
+	}
+	return message.Fields[fd.Index()], true
+}
+
+// GetOneofByField returns the oneof containing field. It reports false if
+// field is not part of a oneof or is not a field of this message.
+func (message *Message) GetOneofByField(field *Field) (*Oneof, bool) {
+	if field == nil || field.Parent != message || field.Oneof == nil {
+		return nil, false
+	}
+	return field.Oneof, true
+}
+
+// HasField reports whether the message has a field with the given proto name.
+func (message *Message) HasField(fieldName string) bool {
+	_, ok := message.FindFieldByName(fieldName)
+	return ok
+}
+
+// HasOneof reports whether the message has a oneof with the given name.
+func (message *Message) HasOneof(oneofName string) bool {
+	return message.Desc.Oneofs().ByName(protoreflect.Name(oneofName)) != nil
+}
+
+// HasExtension reports whether the message declares a nested extension
+// with the given field number.
+func (message *Message) HasExtension(fieldNumber protoreflect.FieldNumber) bool {
+	for _, extension := range message.Extensions {
+		if extension.Desc.Number() == fieldNumber {
+			return true
+		}
+	}
+	return false
+}
+
+// HasNestedMessage reports whether the message declares a nested message
+// with the given name.
+func (message *Message) HasNestedMessage(name string) bool {
+	return message.Desc.Messages().ByName(protoreflect.Name(name)) != nil
+}
+
+// RealFields returns the fields of the message, excluding those contained
+// in a synthetic oneof (i.e. proto3 optional fields).
+func (message *Message) RealFields() []*Field {
+	var fields []*Field
+	for _, field := range message.Fields {
+		if field.Oneof != nil && field.Oneof.IsSynthetic() {
+			continue
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// GetSyntheticFields returns the fields of the message contained in a
+// synthetic oneof (i.e. proto3 optional fields). It is the complement of
+// RealFields.
+func (message *Message) GetSyntheticFields() []*Field {
+	var fields []*Field
+	for _, field := range message.Fields {
+		if field.Oneof != nil && field.Oneof.IsSynthetic() {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// RequiredFields returns the fields of the message with required
+// cardinality. The result is non-nil even if there are none.
+func (message *Message) RequiredFields() []*Field {
+	fields := []*Field{}
+	for _, field := range message.Fields {
+		if field.Desc.Cardinality() == protoreflect.Required {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+func (message *Message) GetOneofs() []*Oneof {
+	return message.Oneofs
+}
+
+func (message *Message) GetEnums() []*Enum {
+	return message.Enums
+}
+
+func (message *Message) GetMessages() []*Message {
+	return message.Messages
+}
+
+func (message *Message) GetFieldCount() int {
+	return len(message.Fields)
+}
+
+func (message *Message) GetOneofCount() int {
+	return len(message.Oneofs)
+}
+
+func (message *Message) GetEnumCount() int {
+	return len(message.Enums)
+}
+
+func (message *Message) GetMessageCount() int {
+	return len(message.Messages)
+}
+
+func (message *Message) GetExtensionCount() int {
+	return len(message.Extensions)
+}
+
+// IsEmpty reports whether the message declares no fields, oneofs or
+// nested enums and messages.
+func (message *Message) IsEmpty() bool {
+	return len(message.Fields) == 0 && len(message.Oneofs) == 0 &&
+		len(message.Enums) == 0 && len(message.Messages) == 0
+}
+
+// GetProtoFilePath returns the path of the .proto file declaring the message.
+func (message *Message) GetProtoFilePath() string {
+	return message.Desc.ParentFile().Path()
+}
+
 func (message *Message) GetJavaPackage() string {
 	fileDescriptor := message.Desc.ParentFile()
 	fileOptions := fileDescriptor.Options().(*descriptorpb.FileOptions)
@@ -230,9 +796,181 @@ func (message *Message) GetJavaPackage() string {
 	return javaPackage
 }
 
+// IsProto2 reports whether the message is declared in a proto2 file.
+func (message *Message) IsProto2() bool {
+	return message.Desc.ParentFile().Syntax() == protoreflect.Proto2
+}
+
+// IsProto3 reports whether the message is declared in a proto3 file.
+func (message *Message) IsProto3() bool {
+	return message.Desc.ParentFile().Syntax() == protoreflect.Proto3
+}
+
+// GetHashCode returns the hex-encoded SHA-256 hash of the deterministic
+// serialization of the message's descriptor proto, for cache invalidation.
+func (message *Message) GetHashCode() string {
+	return hashProto(message.proto)
+}
+
+// GetDescriptorProto returns the DescriptorProto that declares the message.
+func (message *Message) GetDescriptorProto() *descriptorpb.DescriptorProto {
+	return message.proto
+}
+
+// GetMessageOptions returns the message's options, or empty options if none
+// are set.
+func (message *Message) GetMessageOptions() *descriptorpb.MessageOptions {
+	options, _ := message.Desc.Options().(*descriptorpb.MessageOptions)
+	if options == nil {
+		return &descriptorpb.MessageOptions{}
+	}
+	return options
+}
+
+// IsAbstract reports whether the message sets a bool message option named
+// abstract_message, such as (my.abstract_message) = true. The option's
+// extension type must have been given to Generator.RegisterExtension.
+func (message *Message) IsAbstract() bool {
+	for _, xt := range message.File.gen.extensions {
+		xd := xt.TypeDescriptor()
+		if xd.Name() != "abstract_message" || xd.Kind() != protoreflect.BoolKind || xd.IsList() ||
+			xd.ContainingMessage().FullName() != "google.protobuf.MessageOptions" {
+			continue
+		}
+		if v, ok := ExtractMessageOptions(message).GetBool(xt); ok {
+			return v
+		}
+	}
+	return false
+}
+
+// GetStringRepeatedOption returns the values of the repeated string custom
+// option xt set on the message, e.g. a list of interfaces to implement.
+func (message *Message) GetStringRepeatedOption(xt protoreflect.ExtensionType) ([]string, bool) {
+	return stringRepeatedOption(message.Desc.Options(), xt)
+}
+
+// GetSizeHint returns a rough estimate of the serialized size of the message
+// with every field set once, suitable for pre-allocating buffers.
+// Strings and bytes are assumed to average 8 bytes, and recursive message
+// references are counted once.
+func (message *Message) GetSizeHint() int {
+	return message.sizeHint(make(map[*Message]bool))
+}
+
+func (message *Message) sizeHint(visiting map[*Message]bool) int {
+	if visiting[message] {
+		return 0
+	}
+	visiting[message] = true
+	defer delete(visiting, message)
+
+	var n int
+	for _, field := range message.Fields {
+		n += protowire.SizeTag(field.Desc.Number())
+
+		switch field.Desc.Kind() {
+		case protoreflect.BoolKind:
+			n++
+		case protoreflect.EnumKind, protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Uint32Kind:
+			n += 5
+		case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Uint64Kind:
+			n += 10
+		case protoreflect.Fixed32Kind, protoreflect.Sfixed32Kind, protoreflect.FloatKind:
+			n += 4
+		case protoreflect.Fixed64Kind, protoreflect.Sfixed64Kind, protoreflect.DoubleKind:
+			n += 8
+		case protoreflect.StringKind, protoreflect.BytesKind:
+			n += 1 + 8
+		case protoreflect.MessageKind:
+			size := field.Message.sizeHint(visiting)
+			n += protowire.SizeVarint(uint64(size)) + size
+		case protoreflect.GroupKind:
+			n += field.Message.sizeHint(visiting) + protowire.SizeTag(field.Desc.Number())
+		}
+	}
+	return n
+}
+
+// GetExtensionRanges returns the field numbers reserved for extensions.
+func (message *Message) GetExtensionRanges() FieldNumberSet {
+	return NewFieldNumberSet(message.Desc.ExtensionRanges())
+}
+
+// GetFieldNumbers returns the numbers of the message's fields in
+// ascending order.
+func (message *Message) GetFieldNumbers() []protoreflect.FieldNumber {
+	numbers := make([]protoreflect.FieldNumber, 0, len(message.Fields))
+	for _, field := range message.Fields {
+		numbers = append(numbers, field.Desc.Number())
+	}
+	sort.Slice(numbers, func(i, j int) bool { return numbers[i] < numbers[j] })
+	return numbers
+}
+
+// GetExtensionFieldNumbers returns the bounds of the message's extension
+// ranges in ascending order: the first and last (inclusive) number of each
+// range, one pair after another. It returns nil if the message is not
+// extendable.
+func (message *Message) GetExtensionFieldNumbers() []protoreflect.FieldNumber {
+	var numbers []protoreflect.FieldNumber
+	for _, r := range message.GetExtensionRanges() {
+		numbers = append(numbers, r[0], r[1]-1)
+	}
+	return numbers
+}
+
+// GetAllFieldNumbers returns the numbers of the message's fields together
+// with the numbers in its extension ranges, in ascending order. Since
+// ranges such as "extensions 1000 to max" are huge, at most limit extension
+// numbers are included: once limit is reached, the remaining extension
+// numbers, the largest ones, are silently left out. A limit of 0 or less
+// includes none.
+func (message *Message) GetAllFieldNumbers(limit int) []protoreflect.FieldNumber {
+	numbers := message.GetFieldNumbers()
+	for _, r := range message.GetExtensionRanges() {
+		for n := r[0]; n < r[1] && limit > 0; n++ {
+			numbers = append(numbers, n)
+			limit--
+		}
+	}
+	sort.Slice(numbers, func(i, j int) bool { return numbers[i] < numbers[j] })
+	return numbers
+}
+
+// A FieldNumberSet is a set of field numbers, stored as sorted half-open
+// [start, end) ranges.
+type FieldNumberSet [][2]protoreflect.FieldNumber
+
+// NewFieldNumberSet returns a FieldNumberSet containing the given ranges.
+func NewFieldNumberSet(ranges protoreflect.FieldRanges) FieldNumberSet {
+	set := make(FieldNumberSet, ranges.Len())
+	for i := range set {
+		set[i] = ranges.Get(i)
+	}
+	sort.Slice(set, func(i, j int) bool { return set[i][0] < set[j][0] })
+	return set
+}
+
+// Contains reports whether n falls within one of the ranges.
+func (set FieldNumberSet) Contains(n protoreflect.FieldNumber) bool {
+	i := sort.Search(len(set), func(i int) bool { return set[i][1] > n })
+	return i < len(set) && set[i][0] <= n
+}
+
+// Size returns the total count of field numbers in all ranges.
+func (set FieldNumberSet) Size() int {
+	var n int
+	for _, r := range set {
+		n += int(r[1] - r[0])
+	}
+	return n
+}
+
 // A Field describes a message field.
 type Field struct {
 	Desc protoreflect.FieldDescriptor
+	File *File // file in which this field is declared
 
 	Parent *Message // message in which this field is declared; nil if top-level extension
 
@@ -242,17 +980,378 @@ type Field struct {
 	Message  *Message // type for message or group fields; nil otherwise
 
 	Comments CommentSet // comments associated with this field
+
+	proto *descriptorpb.FieldDescriptorProto
 }
 
 func newField(gen *Generator, f *File, message *Message, desc protoreflect.FieldDescriptor) *Field {
 	field := &Field{
 		Desc:     desc,
 		Parent:   message,
-		Comments: MakeCommentSet(f.Desc.SourceLocations().ByDescriptor(desc)),
+		Comments: MakeCommentSet(f.SourceLocationFor(desc)),
+		File:     f,
+	}
+
+	switch {
+	case message == nil:
+		field.proto = f.Proto.GetExtension()[desc.Index()]
+	case desc.IsExtension():
+		field.proto = message.proto.GetExtension()[desc.Index()]
+	default:
+		field.proto = message.proto.GetField()[desc.Index()]
 	}
 	return field
 }
 
+// descriptorProto returns the DescriptorProto in f.Proto that declares desc.
+func descriptorProto(f *File, desc protoreflect.MessageDescriptor) *descriptorpb.DescriptorProto {
+	if parent, ok := desc.Parent().(protoreflect.MessageDescriptor); ok {
+		return descriptorProto(f, parent).GetNestedType()[desc.Index()]
+	}
+	return f.Proto.GetMessageType()[desc.Index()]
+}
+
+// enumDescriptorProto returns the EnumDescriptorProto in f.Proto that declares desc.
+func enumDescriptorProto(f *File, desc protoreflect.EnumDescriptor) *descriptorpb.EnumDescriptorProto {
+	if parent, ok := desc.Parent().(protoreflect.MessageDescriptor); ok {
+		return descriptorProto(f, parent).GetEnumType()[desc.Index()]
+	}
+	return f.Proto.GetEnumType()[desc.Index()]
+}
+
+// GetFile returns the file in which the field or extension is declared.
+func (field *Field) GetFile() *File {
+	return field.File
+}
+
+func (field *Field) GetProtoName() string {
+	return string(field.Desc.Name())
+}
+
+func (field *Field) GetFullProtoName() string {
+	return string(field.Desc.FullName())
+}
+
+func (field *Field) GetIndex() int {
+	return field.Desc.Index()
+}
+
+// GetFieldDescriptorProto returns the FieldDescriptorProto that declares the field.
+func (field *Field) GetFieldDescriptorProto() *descriptorpb.FieldDescriptorProto {
+	return field.proto
+}
+
+// GetFieldOptions returns the field's options, or empty options if none
+// are set.
+func (field *Field) GetFieldOptions() *descriptorpb.FieldOptions {
+	options, _ := field.Desc.Options().(*descriptorpb.FieldOptions)
+	if options == nil {
+		return &descriptorpb.FieldOptions{}
+	}
+	return options
+}
+
+// GetCType returns the field's ctype option, STRING if unset.
+func (field *Field) GetCType() descriptorpb.FieldOptions_CType {
+	return field.GetFieldOptions().GetCtype()
+}
+
+// GetEnumDescriptorProto returns the EnumDescriptorProto declaring the type
+// of an enum field, or nil if the field is not an enum.
+func (field *Field) GetEnumDescriptorProto() *descriptorpb.EnumDescriptorProto {
+	if field.Enum == nil {
+		return nil
+	}
+	return enumDescriptorProto(field.Enum.File, field.Enum.Desc)
+}
+
+// GetProtoFilePath returns the path of the .proto file declaring the field
+// or extension.
+func (field *Field) GetProtoFilePath() string {
+	return field.Desc.ParentFile().Path()
+}
+
+// GetExtendee returns the message extended by an extension field,
+// or nil if the field is not an extension.
+func (field *Field) GetExtendee() *Message {
+	return field.Extendee
+}
+
+// IsTopLevelExtension reports whether the field is an extension declared
+// at file scope rather than nested within a message.
+func (field *Field) IsTopLevelExtension() bool {
+	return field.Parent == nil && field.Extendee != nil
+}
+
+// GetExtensionType returns the Go extension type registered in
+// protoregistry.GlobalTypes for an extension field. It reports false if the
+// field is not an extension or no type is registered for it.
+func (field *Field) GetExtensionType() (protoreflect.ExtensionType, bool) {
+	if !field.Desc.IsExtension() {
+		return nil, false
+	}
+
+	xt, err := protoregistry.GlobalTypes.FindExtensionByName(field.Desc.FullName())
+	if err != nil {
+		return nil, false
+	}
+	return xt, true
+}
+
+// GetStringRepeatedOption returns the values of the repeated string custom
+// option xt set on the field.
+func (field *Field) GetStringRepeatedOption(xt protoreflect.ExtensionType) ([]string, bool) {
+	return stringRepeatedOption(field.Desc.Options(), xt)
+}
+
+// IsAny reports whether the field is of type google.protobuf.Any.
+func (field *Field) IsAny() bool {
+	return field.Message != nil && field.Message.Desc.FullName() == "google.protobuf.Any"
+}
+
+// GetTypeURL returns the value of a custom string option named "type_url"
+// set on the field, or "" if there is none. Only options whose extension
+// type is registered with protoregistry.GlobalTypes can be found.
+func (field *Field) GetTypeURL() string {
+	var typeURL string
+	field.Desc.Options().ProtoReflect().Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		if fd.IsExtension() && fd.Name() == "type_url" && fd.Kind() == protoreflect.StringKind && !fd.IsList() {
+			typeURL = v.String()
+			return false
+		}
+		return true
+	})
+	return typeURL
+}
+
+// GetGoZeroValue returns the Go literal for the zero value of the field as
+// protoc-gen-go declares it: "nil" for messages, bytes, lists, maps and
+// scalars with explicit presence (which are pointers), and otherwise "0",
+// "false" or `""` according to the field's kind.
+func (field *Field) GetGoZeroValue() string {
+	desc := field.Desc
+	inRealOneof := field.Oneof != nil && !field.Oneof.IsSynthetic()
+	switch {
+	case desc.IsList(), desc.IsMap(), desc.Message() != nil, desc.Kind() == protoreflect.BytesKind:
+		return "nil"
+	case desc.HasPresence() && !inRealOneof:
+		return "nil"
+	}
+
+	switch desc.Kind() {
+	case protoreflect.BoolKind:
+		return "false"
+	case protoreflect.StringKind:
+		return `""`
+	default:
+		return "0"
+	}
+}
+
+// GetDefaultBoolValue returns the explicit default of a bool field.
+// It reports false if the field has another kind or no explicit default,
+// as is always the case in proto3.
+func (field *Field) GetDefaultBoolValue() (bool, bool) {
+	v, ok := field.defaultValue(protoreflect.BoolKind)
+	if !ok {
+		return false, false
+	}
+	return v.Bool(), true
+}
+
+// GetDefaultInt64Value returns the explicit default of a signed integer field.
+func (field *Field) GetDefaultInt64Value() (int64, bool) {
+	v, ok := field.defaultValue(
+		protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind)
+	if !ok {
+		return 0, false
+	}
+	return v.Int(), true
+}
+
+// GetDefaultFloat64Value returns the explicit default of a float or double field.
+func (field *Field) GetDefaultFloat64Value() (float64, bool) {
+	v, ok := field.defaultValue(protoreflect.FloatKind, protoreflect.DoubleKind)
+	if !ok {
+		return 0, false
+	}
+	return v.Float(), true
+}
+
+// GetDefaultStringValue returns the explicit default of a string field.
+func (field *Field) GetDefaultStringValue() (string, bool) {
+	v, ok := field.defaultValue(protoreflect.StringKind)
+	if !ok {
+		return "", false
+	}
+	return v.String(), true
+}
+
+// GetDefaultBytesValue returns the explicit default of a bytes field.
+func (field *Field) GetDefaultBytesValue() ([]byte, bool) {
+	v, ok := field.defaultValue(protoreflect.BytesKind)
+	if !ok {
+		return nil, false
+	}
+	return append([]byte(nil), v.Bytes()...), true
+}
+
+// defaultValue returns the explicit default of a singular field of one of
+// the given kinds.
+func (field *Field) defaultValue(kinds ...protoreflect.Kind) (protoreflect.Value, bool) {
+	desc := field.Desc
+	if desc.IsList() || !desc.HasDefault() {
+		return protoreflect.Value{}, false
+	}
+	for _, kind := range kinds {
+		if desc.Kind() == kind {
+			return desc.Default(), true
+		}
+	}
+	return protoreflect.Value{}, false
+}
+
+// GetJavaType returns the Java type of the field: a primitive such as "int"
+// for singular scalars, "java.util.List<T>" for repeated fields and
+// "java.util.Map<K, V>" for maps. Messages and enums are named by their
+// fully qualified class name, e.g. "com.example.FooOuterClass.Outer.Inner".
+func (field *Field) GetJavaType() string {
+	switch {
+	case field.Desc.IsMap():
+		return "java.util.Map<" + javaBoxedType(field.Desc.MapKey()) + ", " + javaBoxedType(field.Desc.MapValue()) + ">"
+	case field.Desc.IsList():
+		return "java.util.List<" + javaBoxedType(field.Desc) + ">"
+	}
+
+	switch field.Desc.Kind() {
+	case protoreflect.BoolKind:
+		return "boolean"
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return "int"
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind,
+		protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return "long"
+	case protoreflect.FloatKind:
+		return "float"
+	case protoreflect.DoubleKind:
+		return "double"
+	}
+	return javaBoxedType(field.Desc)
+}
+
+// GetJavaBoxedType is like GetJavaType but returns the boxed type of
+// primitives, e.g. "Integer" instead of "int", for use in generic contexts.
+func (field *Field) GetJavaBoxedType() string {
+	if field.Desc.IsMap() || field.Desc.IsList() {
+		return field.GetJavaType()
+	}
+	return javaBoxedType(field.Desc)
+}
+
+// javaBoxedType returns the boxed Java type of a single value of desc.
+func javaBoxedType(desc protoreflect.FieldDescriptor) string {
+	switch desc.Kind() {
+	case protoreflect.BoolKind:
+		return "Boolean"
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return "Integer"
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind,
+		protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return "Long"
+	case protoreflect.FloatKind:
+		return "Float"
+	case protoreflect.DoubleKind:
+		return "Double"
+	case protoreflect.StringKind:
+		return "String"
+	case protoreflect.BytesKind:
+		return "com.google.protobuf.ByteString"
+	case protoreflect.EnumKind:
+		return javaClassName(desc.Enum())
+	default:
+		return javaClassName(desc.Message())
+	}
+}
+
+// javaClassName returns the Java class name of a message or enum: its
+// file's Java package followed by its name relative to the proto package.
+// Unless java_multiple_files is set, the name is nested in the file's
+// outer class, e.g. "com.example.FooOuterClass.Msg".
+func javaClassName(desc protoreflect.Descriptor) string {
+	fd := desc.ParentFile()
+	options := fd.Options().(*descriptorpb.FileOptions)
+	name := strings.TrimPrefix(string(desc.FullName()), string(fd.Package())+".")
+	if !options.GetJavaMultipleFiles() {
+		name = javaOuterClassName(fd) + "." + name
+	}
+
+	javaPackage := options.GetJavaPackage()
+	if javaPackage == "" {
+		javaPackage = string(fd.Package())
+	}
+	if javaPackage == "" {
+		return name
+	}
+	return javaPackage + "." + name
+}
+
+// GetJSONSchema returns a minimal JSON Schema fragment describing the
+// field's value in the proto3 JSON mapping, e.g. {"type": "string"}.
+// Message types are referenced as {"$ref": "#/definitions/<full name>"}.
+// It is a best-effort helper, not a complete schema generator.
+func (field *Field) GetJSONSchema() map[string]any {
+	switch {
+	case field.Desc.IsMap():
+		return map[string]any{
+			"type":                 "object",
+			"additionalProperties": jsonSchema(field.Desc.MapValue()),
+		}
+	case field.Desc.IsList():
+		return map[string]any{
+			"type":  "array",
+			"items": jsonSchema(field.Desc),
+		}
+	default:
+		return jsonSchema(field.Desc)
+	}
+}
+
+// jsonSchema returns the JSON Schema fragment for a single value of desc.
+func jsonSchema(desc protoreflect.FieldDescriptor) map[string]any {
+	switch desc.Kind() {
+	case protoreflect.BoolKind:
+		return map[string]any{"type": "boolean"}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return map[string]any{"type": "integer", "format": "int32"}
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return map[string]any{"type": "integer", "format": "uint32"}
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return map[string]any{"type": "string", "format": "int64"}
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return map[string]any{"type": "string", "format": "uint64"}
+	case protoreflect.FloatKind:
+		return map[string]any{"type": "number", "format": "float"}
+	case protoreflect.DoubleKind:
+		return map[string]any{"type": "number", "format": "double"}
+	case protoreflect.StringKind:
+		return map[string]any{"type": "string"}
+	case protoreflect.BytesKind:
+		return map[string]any{"type": "string", "format": "byte"}
+	case protoreflect.EnumKind:
+		var names []any
+		for i, vds := 0, desc.Enum().Values(); i < vds.Len(); i++ {
+			names = append(names, string(vds.Get(i).Name()))
+		}
+		return map[string]any{"type": "string", "enum": names}
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return map[string]any{"$ref": "#/definitions/" + string(desc.Message().FullName())}
+	}
+	return map[string]any{}
+}
+
 func (field *Field) resolveDependencies(gen *Generator) error {
 	desc := field.Desc
 
@@ -287,6 +1386,7 @@ func (field *Field) resolveDependencies(gen *Generator) error {
 // A Oneof describes a message oneof.
 type Oneof struct {
 	Desc protoreflect.OneofDescriptor
+	File *File // file in which this oneof is declared
 
 	Parent *Message // message in which this oneof is declared
 
@@ -298,17 +1398,42 @@ type Oneof struct {
 func newOneof(gen *Generator, f *File, message *Message, desc protoreflect.OneofDescriptor) *Oneof {
 	return &Oneof{
 		Desc:     desc,
+		File:     f,
 		Parent:   message,
-		Comments: MakeCommentSet(f.Desc.SourceLocations().ByDescriptor(desc)),
+		Comments: MakeCommentSet(f.SourceLocationFor(desc)),
 	}
 }
 
+// GetFile returns the file in which the oneof is declared.
+func (o *Oneof) GetFile() *File {
+	return o.File
+}
+
+func (o *Oneof) FullName() string {
+	return string(o.Desc.FullName())
+}
+
+func (o *Oneof) GetIndex() int {
+	return o.Desc.Index()
+}
+
+// IsSynthetic reports whether the oneof was generated by protoc to back a
+// proto3 optional field.
+func (o *Oneof) IsSynthetic() bool {
+	return o.Desc.IsSynthetic()
+}
+
+func (o *Oneof) GetFields() []*Field {
+	return o.Fields
+}
+
 // Extension is an alias of [Field] for documentation.
 type Extension = Field
 
 // A Service describes a service.
 type Service struct {
 	Desc protoreflect.ServiceDescriptor
+	File *File // file in which this service is declared
 
 	Methods  []*Method  // service method declarations
 	Comments CommentSet // comments associated with this service
@@ -317,7 +1442,8 @@ type Service struct {
 func newService(gen *Generator, f *File, desc protoreflect.ServiceDescriptor) *Service {
 	service := &Service{
 		Desc:     desc,
-		Comments: MakeCommentSet(f.Desc.SourceLocations().ByDescriptor(desc)),
+		File:     f,
+		Comments: MakeCommentSet(f.SourceLocationFor(desc)),
 	}
 
 	for i, mds := 0, desc.Methods(); i < mds.Len(); i++ {
@@ -331,9 +1457,69 @@ func (s *Service) GetName() string {
 	return string(s.Desc.Name())
 }
 
+// GetFile returns the file in which the service is declared.
+func (s *Service) GetFile() *File {
+	return s.File
+}
+
+// GetServiceOptions returns the service's options, or empty options if none
+// are set.
+func (s *Service) GetServiceOptions() *descriptorpb.ServiceOptions {
+	options, _ := s.Desc.Options().(*descriptorpb.ServiceOptions)
+	if options == nil {
+		return &descriptorpb.ServiceOptions{}
+	}
+	return options
+}
+
+func (s *Service) GetMethods() []*Method {
+	return s.Methods
+}
+
+func (s *Service) GetMethodCount() int {
+	return len(s.Methods)
+}
+
+// IsEmpty reports whether the service declares no methods.
+func (s *Service) IsEmpty() bool {
+	return len(s.Methods) == 0
+}
+
+// GetMethodNames returns the names of the service's methods.
+func (s *Service) GetMethodNames() []string {
+	names := make([]string, len(s.Methods))
+	for i, method := range s.Methods {
+		names[i] = method.GetName()
+	}
+	return names
+}
+
+// GetStringRepeatedOption returns the values of the repeated string custom
+// option xt set on the service.
+func (s *Service) GetStringRepeatedOption(xt protoreflect.ExtensionType) ([]string, bool) {
+	return stringRepeatedOption(s.Desc.Options(), xt)
+}
+
+// FilterMethods returns the methods for which predicate returns true.
+func (s *Service) FilterMethods(predicate func(*Method) bool) []*Method {
+	var methods []*Method
+	for _, method := range s.Methods {
+		if predicate(method) {
+			methods = append(methods, method)
+		}
+	}
+	return methods
+}
+
+// GetProtoFilePath returns the path of the .proto file declaring the service.
+func (s *Service) GetProtoFilePath() string {
+	return s.Desc.ParentFile().Path()
+}
+
 // A Method describes a method in a service.
 type Method struct {
 	Desc protoreflect.MethodDescriptor
+	File *File // file in which this method is declared
 
 	Parent *Service // service in which this method is declared
 
@@ -346,8 +1532,9 @@ type Method struct {
 func newMethod(gen *Generator, f *File, service *Service, desc protoreflect.MethodDescriptor) *Method {
 	method := &Method{
 		Desc:     desc,
+		File:     f,
 		Parent:   service,
-		Comments: MakeCommentSet(f.Desc.SourceLocations().ByDescriptor(desc)),
+		Comments: MakeCommentSet(f.SourceLocationFor(desc)),
 	}
 	return method
 }
@@ -376,11 +1563,60 @@ func (method *Method) GetName() string {
 	return string(method.Desc.Name())
 }
 
+func (method *Method) GetParent() *Service {
+	return method.Parent
+}
+
+// GetFile returns the file in which the method is declared.
+func (method *Method) GetFile() *File {
+	return method.File
+}
+
+func (method *Method) GetIndex() int {
+	return method.Desc.Index()
+}
+
+func (method *Method) GetInput() *Message {
+	return method.Input
+}
+
+func (method *Method) GetOutput() *Message {
+	return method.Output
+}
+
+// GetInputFullName returns the full name of the method's input type,
+// or "" if it is unresolved.
+func (method *Method) GetInputFullName() string {
+	if method.Input == nil {
+		return ""
+	}
+	return string(method.Input.Desc.FullName())
+}
+
+// GetOutputFullName returns the full name of the method's output type,
+// or "" if it is unresolved.
+func (method *Method) GetOutputFullName() string {
+	if method.Output == nil {
+		return ""
+	}
+	return string(method.Output.Desc.FullName())
+}
+
 func (method *Method) GetDeprecated() bool {
 	options := method.Desc.Options().(*descriptorpb.MethodOptions)
 	return options.GetDeprecated()
 }
 
+// GetMethodOptions returns the method's options, or empty options if none
+// are set.
+func (method *Method) GetMethodOptions() *descriptorpb.MethodOptions {
+	options, _ := method.Desc.Options().(*descriptorpb.MethodOptions)
+	if options == nil {
+		return &descriptorpb.MethodOptions{}
+	}
+	return options
+}
+
 func (method *Method) GetInputStreaming() bool {
 	return method.Desc.IsStreamingClient()
 }
@@ -389,6 +1625,59 @@ func (method *Method) GetOutputStreaming() bool {
 	return method.Desc.IsStreamingServer()
 }
 
+// GetClientStreamingKind returns "streaming" if the client streams requests
+// and "unary" otherwise.
+func (method *Method) GetClientStreamingKind() string {
+	return streamingKind(method.Desc.IsStreamingClient())
+}
+
+// GetServerStreamingKind returns "streaming" if the server streams responses
+// and "unary" otherwise.
+func (method *Method) GetServerStreamingKind() string {
+	return streamingKind(method.Desc.IsStreamingServer())
+}
+
+func streamingKind(streaming bool) string {
+	if streaming {
+		return "streaming"
+	}
+	return "unary"
+}
+
+// GetStringRepeatedOption returns the values of the repeated string custom
+// option xt set on the method.
+func (method *Method) GetStringRepeatedOption(xt protoreflect.ExtensionType) ([]string, bool) {
+	return stringRepeatedOption(method.Desc.Options(), xt)
+}
+
+// hashProto returns the hex-encoded SHA-256 hash of the deterministic
+// serialization of m.
+func hashProto(m proto.Message) string {
+	b, err := proto.MarshalOptions{Deterministic: true}.Marshal(m)
+	if err != nil {
+		panic(fmt.Sprintf("protogen: cannot marshal %v: %v", m.ProtoReflect().Descriptor().FullName(), err))
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// stringRepeatedOption returns the values of the repeated string extension
+// xt set on opts. It reports false if xt is not a repeated string extension
+// of opts or is not set.
+func stringRepeatedOption(opts proto.Message, xt protoreflect.ExtensionType) ([]string, bool) {
+	xd := xt.TypeDescriptor()
+	if !xd.IsList() || xd.Kind() != protoreflect.StringKind || !proto.HasExtension(opts, xt) {
+		return nil, false
+	}
+
+	list := opts.ProtoReflect().Get(xd).List()
+	values := make([]string, list.Len())
+	for i := range values {
+		values[i] = list.Get(i).String()
+	}
+	return values, true
+}
+
 // CommentSet is a set of leading and trailing comments associated
 // with a .proto descriptor declaration.
 type CommentSet struct {
@@ -410,6 +1699,38 @@ func MakeCommentSet(loc protoreflect.SourceLocation) CommentSet {
 	}
 }
 
+// FirstSentence returns the first sentence of the leading comments: the text
+// of the first paragraph up to and including the first '.', '?' or '!' that
+// is followed by whitespace or ends the paragraph. If there is no such
+// terminator, the first line is returned.
+func (cs CommentSet) FirstSentence() string {
+	var lines []string
+	for _, line := range strings.Split(string(cs.Leading), "\n") {
+		line = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "//"))
+		if line == "" {
+			if len(lines) > 0 {
+				break
+			}
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+
+	text := strings.Join(lines, " ")
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case '.', '?', '!':
+			if i+1 == len(text) || text[i+1] == ' ' {
+				return text[:i+1]
+			}
+		}
+	}
+	return lines[0]
+}
+
 // Comments is a comments string as provided by protoc.
 type Comments string
 
@@ -417,16 +1738,143 @@ type Comments string
 // ensuring that there is a trailing newline.
 // An empty comment is formatted as an empty string.
 func (c Comments) String() string {
+	return c.prefixLines("//")
+}
+
+// AsRustDoc formats the comments as Rust outer doc comments by inserting
+// /// to the start of each line.
+func (c Comments) AsRustDoc() string {
+	return c.prefixLines("///")
+}
+
+// AsRustInnerDoc formats the comments as Rust inner doc comments by
+// inserting //! to the start of each line.
+func (c Comments) AsRustInnerDoc() string {
+	return c.prefixLines("//!")
+}
+
+func (c Comments) prefixLines(prefix string) string {
 	if c == "" {
 		return ""
 	}
 	var b []byte
 	for _, line := range strings.Split(strings.TrimSuffix(string(c), "\n"), "\n") {
-		b = append(b, "//"...)
+		b = append(b, prefix...)
 		b = append(b, line...)
 		b = append(b, "\n"...)
 	}
 	return string(b)
 }
 
+// Deprecated reports whether the comments mark the declaration as deprecated
+// by convention, that is, whether any line starts with "@deprecated",
+// "Deprecated:" or "deprecated" (ignoring case).
+func (c Comments) Deprecated() bool {
+	for _, line := range strings.Split(string(c), "\n") {
+		words := strings.Fields(line)
+		if len(words) == 0 {
+			continue
+		}
+		word := strings.TrimSuffix(strings.TrimPrefix(words[0], "@"), ":")
+		if strings.EqualFold(word, "deprecated") {
+			return true
+		}
+	}
+	return false
+}
+
+// Paragraphs splits the comments into paragraphs separated by blank lines.
+// Each paragraph is trimmed of surrounding whitespace.
+func (c Comments) Paragraphs() []string {
+	var paragraphs []string
+	var lines []string
+	flush := func() {
+		if len(lines) > 0 {
+			paragraphs = append(paragraphs, strings.TrimSpace(strings.Join(lines, "\n")))
+			lines = nil
+		}
+	}
+
+	for _, line := range strings.Split(string(c), "\n") {
+		if strings.TrimSpace(line) == "" {
+			flush()
+			continue
+		}
+		lines = append(lines, line)
+	}
+	flush()
+	return paragraphs
+}
+
+// ContainsTag reports whether any line of the comments contains tag,
+// such as "+build" or "@tag:". It reports false for an empty tag.
+func (c Comments) ContainsTag(tag string) bool {
+	if tag == "" {
+		return false
+	}
+	for _, line := range strings.Split(string(c), "\n") {
+		if strings.Contains(line, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// FindTagValue returns the text following the first occurrence of tag on
+// the same line, trimmed of surrounding whitespace. It returns "" if tag
+// does not occur.
+func (c Comments) FindTagValue(tag string) string {
+	for _, line := range strings.Split(string(c), "\n") {
+		if _, value, ok := strings.Cut(line, tag); ok {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
+
+// WithMaxLength returns the comments truncated to at most n bytes,
+// not counting a trailing newline. Truncation happens at a word boundary
+// where possible and is marked with a trailing "...", unless n is too small
+// to hold it, in which case the text is cut at a rune boundary without one.
+func (c Comments) WithMaxLength(n int) Comments {
+	const ellipsis = "..."
+
+	text := strings.TrimSuffix(string(c), "\n")
+	if len(text) <= n {
+		return c
+	}
+
+	var truncated string
+	if n < len(ellipsis) {
+		// There is no room for the ellipsis.
+		truncated = text[:runeBoundary(text, n)]
+		if truncated == "" {
+			return ""
+		}
+	} else {
+		limit := n - len(ellipsis)
+		cut := strings.LastIndexAny(text[:limit+1], " \t\n")
+		if cut <= 0 {
+			cut = runeBoundary(text, limit)
+		}
+		truncated = strings.TrimRight(text[:cut], " \t\n") + ellipsis
+	}
+	if strings.HasSuffix(string(c), "\n") {
+		truncated += "\n"
+	}
+	return Comments(truncated)
+}
+
+// runeBoundary returns the largest index no greater than n, or 0 if n is
+// negative, at which text can be cut without splitting a rune.
+func runeBoundary(text string, n int) int {
+	if n <= 0 {
+		return 0
+	}
+	for n > 0 && !utf8.RuneStart(text[n]) {
+		n--
+	}
+	return n
+}
+
 //https://github.com/protocolbuffers/protobuf/blob/main/src/google/protobuf/descriptor.proto